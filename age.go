@@ -0,0 +1,102 @@
+package main
+
+import (
+	"image"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Generations after which a live cell counts as stable rather than young
+const MATURE_AGE = 16
+
+// Age classes, the indices of a palette's colors. Trails take the indices
+// after STABLE, one per generation since the cell died.
+const (
+	NEWBORN = 1
+	YOUNG   = 2
+	STABLE  = 3
+)
+
+// ageTracker follows how long the cells on screen have been alive, and how
+// long ago the ones that died did
+type ageTracker struct {
+	// Board cells watched, ages are indexed relative to its corner
+	rect image.Rectangle
+	// Generations alive, or minus the generations since dying, 0 for cells
+	// that have been dead longer than a trail lasts
+	ages       [][]int
+	generation int
+}
+
+// at returns the age of the board cell p and whether it's being watched
+func (t *ageTracker) at(p image.Point) (int, bool) {
+	if t.ages == nil || !p.In(t.rect) {
+		return 0, false
+	}
+	return t.ages[p.Y-t.rect.Min.Y][p.X-t.rect.Min.X], true
+}
+
+// Observe ages the cells of rect by one generation. Anything but the next
+// generation, e.g. after rewinding or skipping ahead, can't be aged: cells
+// alive by then count as stable and trails are dropped. So do cells that
+// just came into rect.
+func (t *ageTracker) Observe(u life.Universe, rect image.Rectangle) {
+	gen := u.Generation()
+	if t.ages != nil && gen == t.generation && rect == t.rect {
+		return
+	}
+	next := t.ages != nil && gen == t.generation+1
+	cells := u.Region(rect.Min.Y, rect.Min.X, rect.Dy(), rect.Dx())
+	ages := make([][]int, rect.Dy())
+	for i := range ages {
+		ages[i] = make([]int, rect.Dx())
+		for j, alive := range cells[i] {
+			prev, ok := t.at(rect.Min.Add(image.Pt(j, i)))
+			switch {
+			case !next || !ok:
+				if alive {
+					ages[i][j] = MATURE_AGE
+				}
+			case alive:
+				ages[i][j] = min(max(prev, 0)+1, MATURE_AGE)
+			case prev > 0:
+				ages[i][j] = -1
+			case prev < 0 && prev > -TRAIL_LENGTH:
+				ages[i][j] = prev - 1
+			}
+		}
+	}
+	t.rect, t.ages, t.generation = rect, ages, gen
+}
+
+// Reset forgets every age, e.g. when age coloring is turned off
+func (t *ageTracker) Reset() {
+	t.ages = nil
+}
+
+// classes returns the age class of the cells in rect, whose live ones are
+// cells, showing at most trail generations of trails. Cells the map shows a
+// generation ahead of the last one observed, or that were drawn since, pass
+// as newborn.
+func (t *ageTracker) classes(cells [][]bool, rect image.Rectangle, trail int) [][]uint8 {
+	classes := make([][]uint8, len(cells))
+	for i, row := range cells {
+		classes[i] = make([]uint8, len(row))
+		for j, alive := range row {
+			age, ok := t.at(rect.Min.Add(image.Pt(j, i)))
+			switch {
+			case alive && age >= MATURE_AGE, alive && !ok:
+				classes[i][j] = STABLE
+			case alive && age > 1:
+				classes[i][j] = YOUNG
+			case alive:
+				classes[i][j] = NEWBORN
+			case age > 0 && trail > 0:
+				classes[i][j] = STABLE + 1
+			case age < 0 && -age <= trail:
+				classes[i][j] = uint8(STABLE - age)
+			}
+		}
+	}
+	return classes
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+func TestAgeTracker(t *testing.T) {
+	g := life.NewGrid(4, 4, life.CONWAY)
+	// Cells watched, the last comes into view once the screen widens
+	cells := []image.Point{{0, 0}, {1, 1}, {3, 0}}
+	var ages ageTracker
+	rect := image.Rect(0, 0, 3, 4)
+	observe := func(gen int) {
+		g.SetGeneration(gen)
+		ages.Observe(g, rect)
+	}
+	check := func(what string, trail int, want ...uint8) {
+		t.Helper()
+		classes := ages.classes(g.Region(rect.Min.Y, rect.Min.X, rect.Dy(), rect.Dx()), rect, trail)
+		got := make([]uint8, len(cells))
+		for i, p := range cells {
+			if p.In(rect) {
+				got[i] = classes[p.Y-rect.Min.Y][p.X-rect.Min.X]
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s, generation %d: classes %v, want %v", what, g.Generation(), got, want)
+		}
+	}
+
+	g.SetCell(0, 0, true)
+	observe(0)
+	check("first look", TRAIL_LENGTH, STABLE, 0, 0)
+	// Drawn since
+	g.SetCell(1, 1, true)
+	observe(0)
+	check("drawn", TRAIL_LENGTH, STABLE, NEWBORN, 0)
+	g.SetCell(0, 0, false)
+	observe(1)
+	check("died", TRAIL_LENGTH, STABLE+1, NEWBORN, 0)
+	observe(2)
+	check("aging", TRAIL_LENGTH, STABLE+2, YOUNG, 0)
+	check("short trail", 1, 0, YOUNG, 0)
+	for gen := 3; gen <= TRAIL_LENGTH; gen++ {
+		observe(gen)
+	}
+	check("end of the trail", TRAIL_LENGTH, STABLE+TRAIL_LENGTH, YOUNG, 0)
+	observe(TRAIL_LENGTH + 1)
+	check("trail gone", TRAIL_LENGTH, 0, YOUNG, 0)
+	for gen := TRAIL_LENGTH + 2; gen < MATURE_AGE; gen++ {
+		observe(gen)
+	}
+	check("not yet mature", TRAIL_LENGTH, 0, YOUNG, 0)
+	observe(MATURE_AGE)
+	check("mature", TRAIL_LENGTH, 0, STABLE, 0)
+
+	// Ages are lost skipping ahead
+	g.SetCell(0, 0, true)
+	g.SetCell(1, 1, false)
+	observe(MATURE_AGE + 5)
+	check("skipped ahead", TRAIL_LENGTH, STABLE, 0, 0)
+
+	// And for cells that come into view
+	g.SetCell(0, 0, false)
+	g.SetCell(1, 1, true)
+	g.SetCell(0, 3, true)
+	rect = image.Rect(0, 0, 4, 4)
+	observe(MATURE_AGE + 6)
+	check("screen widened", TRAIL_LENGTH, STABLE+1, NEWBORN, STABLE)
+
+	ages.Reset()
+	check("reset", TRAIL_LENGTH, 0, STABLE, STABLE)
+}
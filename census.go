@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+
+	ncanvas "github.com/NimbleMarkets/ntcharts/canvas"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Height of the census panel under the map
+const CENSUS_HEIGHT = 5
+
+// Generations the map is run to tell which clusters belong together, enough
+// for the phases of common oscillators and a glider's
+const CENSUS_GENERATIONS = 4
+
+// What the census shows, U cycles through them
+const (
+	CensusHidden = iota
+	CensusPanel
+	// The panel and the names of the objects over the map
+	CensusLabels
+)
+
+var (
+	labelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("226"))
+	unknownStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+)
+
+// census is the objects on the map at one generation
+type census struct {
+	generation int
+	objects    []life.Object
+	// Why there's no census, if there isn't
+	err string
+}
+
+// CensusMsg brings back a census taken off the UI goroutine
+type CensusMsg struct {
+	census *census
+}
+
+// toggleCensus cycles through hiding the census, showing it and labeling
+// the objects on the map. Every time it's shown a census of the map as it
+// is is taken.
+func (m *Model) toggleCensus() tea.Cmd {
+	m.Census = (m.Census + 1) % 3
+	m.clampView()
+	if m.Census == CensusHidden {
+		m.census = nil
+		m.counting = false
+		return nil
+	}
+	return m.takeCensus()
+}
+
+// takeCensus copies the map and classifies its clusters off the UI
+// goroutine, the result comes back as a CensusMsg
+func (m *Model) takeCensus() tea.Cmd {
+	m.counting = true
+	st, rule := m.GameEngine.Stats(), m.GameEngine.Rule()
+	p := m.GameEngine.Pattern()
+	return func() tea.Msg {
+		c := &census{generation: st.Generation}
+		if rule.States > 2 {
+			c.err = "the census needs a Life-like rule"
+			return CensusMsg{c}
+		}
+		u, err := life.NewSparse(rule)
+		if err != nil {
+			c.err = err.Error()
+			return CensusMsg{c}
+		}
+		// Run on a copy, in isolation from the edges of a finite map
+		u.PlacePattern(p, st.Y, st.X)
+		for _, cells := range life.Separate(u, CENSUS_GENERATIONS) {
+			c.objects = append(c.objects, life.Classify(cells, rule))
+		}
+		return CensusMsg{c}
+	}
+}
+
+// renderCensus draws a count of every object by kind under the map
+func (m *Model) renderCensus() string {
+	lines := make([]string, CENSUS_HEIGHT)
+	if m.census == nil {
+		lines[0] = colors[2].Render("CENSUS: counting objects...")
+		return strings.Join(lines, "\n")
+	}
+	labels := "U: labels and recount"
+	if m.Census == CensusLabels {
+		labels = "U: hide"
+	}
+	lines[0] = fmt.Sprintf("CENSUS of gen %d: %d objects  %s", m.census.generation, len(m.census.objects), labels)
+	if m.counting {
+		lines[0] = fmt.Sprintf("CENSUS of gen %d: %d objects, counting again...", m.census.generation, len(m.census.objects))
+	}
+	if m.census.err != "" {
+		lines[0] = fmt.Sprintf("CENSUS of gen %d: %s", m.census.generation, m.census.err)
+	}
+	kinds := []struct {
+		kind  life.Kind
+		title string
+		style lipgloss.Style
+	}{
+		{life.StillLife, "Still lifes", colors[0]},
+		{life.Oscillator, "Oscillators", colors[1]},
+		{life.Spaceship, "Spaceships", colors[2]},
+		{life.Unknown, "Unknown (still changing, or too big)", unknownStyle},
+	}
+	for i, k := range kinds {
+		// Counts by name, the most common first, ties in order of appearance
+		var names []string
+		counts := make(map[string]int)
+		total := 0
+		for _, o := range m.census.objects {
+			if o.Kind != k.kind {
+				continue
+			}
+			total++
+			name := objectLabel(o)
+			if counts[name] == 0 {
+				names = append(names, name)
+			}
+			counts[name]++
+		}
+		if total == 0 {
+			continue
+		}
+		slices.SortStableFunc(names, func(a, b string) int {
+			return cmp.Compare(counts[b], counts[a])
+		})
+		parts := make([]string, len(names))
+		for j, name := range names {
+			parts[j] = fmt.Sprintf("%d %s", counts[name], name)
+		}
+		line := fmt.Sprintf("%s (%d): %s", k.title, total, strings.Join(parts, ", "))
+		if k.kind == life.Unknown {
+			line = fmt.Sprintf("%s: %d", k.title, total)
+		}
+		lines[i+1] = k.style.Render(ansi.Truncate(line, m.Width, "…"))
+	}
+	lines[0] = colors[2].Render(ansi.Truncate(lines[0], m.Width, "…"))
+	return strings.Join(lines, "\n")
+}
+
+// objectLabel returns the name of an object, its apgcode if it has none
+func objectLabel(o life.Object) string {
+	if o.Name != "" {
+		return strings.ToLower(o.Name)
+	}
+	return o.Code
+}
+
+// drawLabels writes the name of every object on screen above it, or below
+// it at the top of the screen, while the census is of the generation shown
+func (m *Model) drawLabels(canvas *ncanvas.Model) {
+	if m.Census != CensusLabels || m.census == nil || m.census.generation != m.GameEngine.Generation() {
+		return
+	}
+	z := ZOOMS[m.Zoom]
+	view := m.viewRect()
+	for _, o := range m.census.objects {
+		if o.Kind == life.Unknown {
+			continue
+		}
+		bounds := image.Rectangle{}
+		for i, c := range o.Cells {
+			cell := image.Rect(c.Col, c.Row, c.Col+1, c.Row+1)
+			if i == 0 {
+				bounds = cell
+			}
+			bounds = bounds.Union(cell)
+		}
+		if !bounds.Overlaps(view) {
+			continue
+		}
+		x := (bounds.Min.X - m.viewLeft) / z.Cols
+		y := (bounds.Min.Y-m.viewTop)/z.Rows - 1
+		if y < 0 {
+			y = (bounds.Max.Y-1-m.viewTop)/z.Rows + 1
+		}
+		canvas.SetStringWithStyle(image.Point{max(x, 0), y}, objectLabel(o), labelStyle)
+	}
+}
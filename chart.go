@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NimbleMarkets/ntcharts/canvas/runes"
+	"github.com/NimbleMarkets/ntcharts/linechart/streamlinechart"
+	"github.com/NimbleMarkets/ntcharts/sparkline"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+const (
+	// Generations kept for the chart and its CSV export
+	CHART_HISTORY = 10000
+	// Terminal rows taken from the map by the chart panel
+	CHART_HEIGHT = 10
+)
+
+// statsLog is the stats of the last generations on screen, oldest first
+type statsLog struct {
+	stats []life.Stats
+}
+
+// Record adds the stats of the generation on screen. Going back to an
+// earlier generation (rewind, clear) drops the ones after it.
+func (l *statsLog) Record(s life.Stats) {
+	for len(l.stats) > 0 && l.stats[len(l.stats)-1].Generation >= s.Generation {
+		l.stats = l.stats[:len(l.stats)-1]
+	}
+	l.stats = append(l.stats, s)
+	if len(l.stats) > CHART_HISTORY {
+		l.stats = l.stats[len(l.stats)-CHART_HISTORY:]
+	}
+}
+
+// tail returns the last n stats at most
+func (l *statsLog) tail(n int) []life.Stats {
+	return l.stats[max(len(l.stats)-n, 0):]
+}
+
+// SaveCSV writes the log in the same format as `cgl run --stats csv`
+func (l *statsLog) SaveCSV(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w, err := newStatsWriter("csv", f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	for _, s := range l.stats {
+		if err := w.Write(s); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// mapHeight returns how many terminal rows the map gets
+func (m *Model) mapHeight() int {
+	height := m.Height
+	if m.ShowChart {
+		height -= CHART_HEIGHT
+	}
+	if m.Census != CensusHidden {
+		height -= CENSUS_HEIGHT
+	}
+	return max(height, 1)
+}
+
+// renderPanels draws the map and, if shown, the census and the chart under it
+func (m *Model) renderPanels() string {
+	view := m.renderMap()
+	if m.GameState == Browsing {
+		view = m.renderPreview()
+	}
+	if m.Census != CensusHidden {
+		view += "\n" + m.renderCensus()
+	}
+	if !m.ShowChart {
+		return view
+	}
+	return view + "\n" + m.renderChart()
+}
+
+// renderChart draws population, births and deaths as lines and the bounding
+// box area as a sparkline under the map
+func (m *Model) renderChart() string {
+	height := min(CHART_HEIGHT, m.Height) - 1
+	sparkWidth := max(m.Width/4, 1)
+	lineWidth := max(m.Width-sparkWidth-1, 1)
+	series := []struct {
+		name  string
+		style lipgloss.Style
+		value func(s life.Stats) int
+	}{
+		{"population", colors[0], func(s life.Stats) int { return s.Population }},
+		{"births", colors[1], func(s life.Stats) int { return s.Births }},
+		{"deaths", colors[2], func(s life.Stats) int { return s.Deaths }},
+	}
+
+	chart := streamlinechart.New(lineWidth, height)
+	for _, ds := range series {
+		chart.SetDataSetStyles(ds.name, runes.ThinLineStyle, ds.style)
+	}
+	spark := sparkline.New(sparkWidth, height, sparkline.WithStyle(colors[1]))
+	for _, s := range m.Chart.tail(lineWidth) {
+		for _, ds := range series {
+			chart.PushDataSet(ds.name, float64(ds.value(s)))
+		}
+	}
+	for _, s := range m.Chart.tail(sparkWidth) {
+		spark.Push(float64(s.Width * s.Height))
+	}
+	chart.DrawAll()
+	spark.Draw()
+
+	var last life.Stats
+	if recent := m.Chart.tail(1); len(recent) > 0 {
+		last = recent[0]
+	}
+	legend := make([]string, 0, len(series)+1)
+	for _, ds := range series {
+		legend = append(legend, ds.style.Render(fmt.Sprintf("%s: %d", ds.name, ds.value(last))))
+	}
+	legend = append(legend, colors[1].Render(fmt.Sprintf("bounding box: %dx%d", last.Width, last.Height)))
+	return lipgloss.JoinVertical(lipgloss.Left,
+		ansi.Truncate(strings.Join(legend, "  "), m.Width, "…"),
+		lipgloss.JoinHorizontal(lipgloss.Top, chart.View(), " ", spark.View()),
+	)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"image"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Largest vim style count, more is ignored
+const MAX_COUNT = 100000
+
+// Color of the keyboard cursor
+var cursorColor = lipgloss.Color("226")
+
+// toggleKeyCursor switches between editing with the mouse and with the
+// keyboard cursor, which starts where the mouse was or in the middle of the
+// screen
+func (m *Model) toggleKeyCursor() {
+	m.KeyCursor = !m.KeyCursor
+	m.count = 0
+	m.visual = false
+	if !m.KeyCursor {
+		return
+	}
+	rows, cols := m.viewSize()
+	m.cursor = image.Pt(m.viewLeft+cols/2, m.viewTop+rows/2)
+	if m.hasCursor {
+		m.cursor = m.cursorBlock().Min
+	}
+	m.moveCursor(0, 0)
+}
+
+// moveCursor moves the keyboard cursor by dy rows and dx columns, stopping
+// at the edges of a finite map, and scrolls the screen to keep it in sight
+func (m *Model) moveCursor(dy, dx int) {
+	m.cursor = m.cursor.Add(image.Pt(dx, dy))
+	if height, width, ok := m.gridSize(); ok {
+		m.cursor.X = max(min(m.cursor.X, width-1), 0)
+		m.cursor.Y = max(min(m.cursor.Y, height-1), 0)
+	}
+	rows, cols := m.viewSize()
+	m.viewTop = max(min(m.viewTop, m.cursor.Y), m.cursor.Y-rows+1)
+	m.viewLeft = max(min(m.viewLeft, m.cursor.X), m.cursor.X-cols+1)
+	m.clampView()
+	if m.visual {
+		m.Selection = image.Rectangle{m.visualFrom, m.cursor}.Canon()
+		m.Selection.Max = m.Selection.Max.Add(image.Pt(1, 1))
+	}
+}
+
+// cursorKey handles the keys of the keyboard cursor, false for the ones it
+// leaves to the rest of the editor
+func (m *Model) cursorKey(msg tea.KeyMsg) bool {
+	count := max(m.count, 1)
+	if msg.Type == tea.KeyRunes {
+		key := string(msg.Runes)
+		if n, err := strconv.Atoi(key); err == nil && len(key) == 1 && (n > 0 || m.count > 0) {
+			m.count = min(m.count*10+n, MAX_COUNT)
+			return true
+		}
+		switch key {
+		case "h":
+			msg.Type = tea.KeyLeft
+		case "j":
+			msg.Type = tea.KeyDown
+		case "k":
+			msg.Type = tea.KeyUp
+		case "l":
+			msg.Type = tea.KeyRight
+		case "v", "V":
+			m.count = 0
+			m.visual = !m.visual
+			m.Selection = image.Rectangle{}
+			if m.visual {
+				m.visualFrom = m.cursor
+				m.moveCursor(0, 0)
+			}
+			return true
+		}
+	}
+	m.count = 0
+	switch msg.Type {
+	case tea.KeyLeft:
+		m.moveCursor(0, -count)
+	case tea.KeyDown:
+		m.moveCursor(count, 0)
+	case tea.KeyUp:
+		m.moveCursor(-count, 0)
+	case tea.KeyRight:
+		m.moveCursor(0, count)
+	case tea.KeySpace:
+		m.History.Begin()
+		if m.Stamp != nil {
+			row, col := m.stampAt()
+			m.view().PlacePattern(m.Stamp, row, col)
+		} else {
+			u := m.edits()
+			u.SetCell(m.cursor.Y, m.cursor.X, !u.Cell(m.cursor.Y, m.cursor.X))
+		}
+		m.History.End()
+	case tea.KeyEsc:
+		switch {
+		case m.visual:
+			m.visual = false
+			m.Selection = image.Rectangle{}
+		case m.Stamp != nil || !m.Selection.Empty():
+			// Put away by the editor
+			return false
+		default:
+			m.KeyCursor = false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// cursorStyle returns how the terminal cell at the keyboard cursor is drawn,
+// at the closest zoom only the half of it that's the cursor is highlighted
+func (m *Model) cursorStyle(cells [][]bool, y, x int, r rune, style lipgloss.Style) (rune, lipgloss.Style) {
+	if m.Zoom != 0 {
+		return r, style.Background(cursorColor)
+	}
+	upper := m.cursor.Y-m.viewTop == y*2
+	other := cells[y*2+1][x]
+	r = '▀'
+	if !upper {
+		other, r = cells[y*2][x], '▄'
+	}
+	style = lipgloss.NewStyle().Foreground(cursorColor)
+	if other {
+		style = style.Background(cyan)
+	}
+	return r, style
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+func TestCursorKeyCount(t *testing.T) {
+	tests := []struct {
+		keys string
+		// Where the cursor and the top left corner of the screen end up
+		want, view image.Point
+	}{
+		{"l", image.Pt(6, 5), image.Pt(0, 0)},
+		{"12l", image.Pt(17, 5), image.Pt(0, 0)},
+		{"3j", image.Pt(5, 8), image.Pt(0, 0)},
+		{"2k", image.Pt(5, 3), image.Pt(0, 0)},
+		// Stopped at the edges of the map
+		{"10h", image.Pt(0, 5), image.Pt(0, 0)},
+		{"20j", image.Pt(5, 19), image.Pt(0, 10)},
+		{"9999999l", image.Pt(39, 5), image.Pt(20, 0)},
+		// A 0 with no count before it isn't one
+		{"0l", image.Pt(6, 5), image.Pt(0, 0)},
+		{"10l", image.Pt(15, 5), image.Pt(0, 0)},
+		// Other keys drop the count
+		{"3xl", image.Pt(6, 5), image.Pt(0, 0)},
+		{"2l3j", image.Pt(7, 8), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		m := testModel(life.NewGrid(20, 40, life.CONWAY))
+		m.Height, m.Width = 5, 20
+		m.KeyCursor = true
+		m.cursor = image.Pt(5, 5)
+		for _, r := range tt.keys {
+			m.cursorKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		if m.cursor != tt.want {
+			t.Errorf("%s: cursor at %v, want %v", tt.keys, m.cursor, tt.want)
+		}
+		if view := image.Pt(m.viewLeft, m.viewTop); view != tt.view {
+			t.Errorf("%s: screen at %v, want %v", tt.keys, view, tt.view)
+		}
+		if m.count != 0 {
+			t.Errorf("%s: count %d left over", tt.keys, m.count)
+		}
+	}
+}
+
+func TestCursorStyle(t *testing.T) {
+	// Two board rows to a terminal row, with live cells sharing one with
+	// board cells 1,0 and 0,3
+	cells := [][]bool{
+		{false, false},
+		{false, true},
+		{true, false},
+		{false, false},
+	}
+	tests := []struct {
+		name   string
+		zoom   int
+		cursor image.Point
+		want   rune
+		fg, bg lipgloss.TerminalColor
+	}{
+		{"upper half", 0, image.Pt(0, 0), '▀', cursorColor, lipgloss.NoColor{}},
+		{"lower half", 0, image.Pt(0, 3), '▄', cursorColor, cyan},
+		{"upper half, live below", 0, image.Pt(1, 0), '▀', cursorColor, cyan},
+		{"zoomed out", 1, image.Pt(0, 2), 'x', lipgloss.NoColor{}, cursorColor},
+	}
+	for _, tt := range tests {
+		m := testModel(life.NewGrid(4, 2, life.CONWAY))
+		m.Zoom, m.cursor = tt.zoom, tt.cursor
+		r, style := m.cursorStyle(cells, tt.cursor.Y/2, tt.cursor.X, 'x', lipgloss.NewStyle())
+		if r != tt.want || style.GetForeground() != tt.fg || style.GetBackground() != tt.bg {
+			t.Errorf("%s: %q on %v over %v, want %q on %v over %v", tt.name, r, style.GetForeground(), style.GetBackground(), tt.want, tt.fg, tt.bg)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+type statsWriter interface {
+	Write(s life.Stats) error
+	Flush() error
+}
+
+type csvStats struct {
+	w *bufio.Writer
+}
+
+func (c *csvStats) Write(s life.Stats) error {
+	_, err := fmt.Fprintf(c.w, "%d,%d,%d,%d,%d,%d,%d,%d\n", s.Generation, s.Population, s.X, s.Y, s.Width, s.Height, s.Births, s.Deaths)
+	return err
+}
+
+func (c *csvStats) Flush() error { return c.w.Flush() }
+
+type jsonStats struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonStats) Write(s life.Stats) error { return j.enc.Encode(s) }
+func (j *jsonStats) Flush() error             { return j.w.Flush() }
+
+func newStatsWriter(format string, w io.Writer) (statsWriter, error) {
+	bw := bufio.NewWriter(w)
+	switch format {
+	case "csv":
+		fmt.Fprintln(bw, "generation,population,x,y,width,height,births,deaths")
+		return &csvStats{bw}, nil
+	case "json":
+		return &jsonStats{bw, json.NewEncoder(bw)}, nil
+	}
+	return nil, fmt.Errorf("unknown stats format %q, expected csv or json", format)
+}
+
+// runHeadless implements `cgl run`: step a pattern for a number of
+// generations without the TUI
+func runHeadless(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	input := fs.String("input", "", "RLE or .cells pattern `file` to start from (required)")
+	output := fs.String("output", "", "write the final map to `file`, - for stdout")
+	generations := fs.Int("generations", 100, "number of generations to run")
+	ruleFlag := fs.String("rule", "", "rule in B/S notation, defaults to the pattern's rule or B3/S23")
+	height := fs.Int("height", DEFAULT_HEIGHT*2, "map height, grown to fit the pattern")
+	width := fs.Int("width", DEFAULT_WIDTH, "map width, grown to fit the pattern")
+	engine := fs.String("engine", "grid", "simulation engine: grid (a --width x --height map), bitgrid (the same bit-packed, for huge maps) or hashlife (unbounded)")
+	topology := fs.String("topology", "", "torus, bounded or infinite, grid defaults to torus")
+	statsFormat := fs.String("stats", "", "print per generation stats to stdout as csv or json (lines)")
+	stopWhenStable := fs.Bool("stop-when-stable", false, "stop once the map dies out or repeats an earlier generation, reported on stderr and in the output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s run --input pattern.rle [options]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *input == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *generations < 0 {
+		headlessFail("--generations must not be negative")
+	}
+	if *statsFormat != "" && *output == "-" {
+		headlessFail("--stats and --output - both write to stdout")
+	}
+	p, err := life.LoadPattern(*input)
+	if err != nil {
+		headlessFail("Unable to load pattern: %v", err)
+	}
+	rule := life.CONWAY
+	if p.Rule != nil {
+		rule = *p.Rule
+	}
+	if *ruleFlag != "" {
+		rule, err = life.ParseRule(*ruleFlag)
+		if err != nil {
+			headlessFail("%v", err)
+		}
+	}
+	var stats statsWriter
+	if *statsFormat != "" {
+		stats, err = newStatsWriter(*statsFormat, os.Stdout)
+		if err != nil {
+			headlessFail("%v", err)
+		}
+	}
+
+	H, W := max(*height, p.Height), max(*width, p.Width)
+	universe, err := newUniverse(*engine, *topology, H, W, rule)
+	if err != nil {
+		headlessFail("%v", err)
+	}
+	universe.PlacePattern(p, (H-p.Height)/2, (W-p.Width)/2)
+	var detector *life.Detector
+	if *stopWhenStable {
+		detector = life.NewDetector()
+	}
+	cycle, err := simulate(universe, *generations, stats, detector)
+	if err != nil {
+		headlessFail("Unable to write stats: %v", err)
+	}
+
+	result := universe.Pattern()
+	result.Name = p.Name
+	if cycle != nil {
+		fmt.Fprintln(os.Stderr, cycle)
+		result.Comments = append(result.Comments, cycle.String())
+	}
+	switch *output {
+	case "":
+	case "-":
+		err = life.WriteRLE(os.Stdout, result)
+	default:
+		err = life.SavePattern(*output, result)
+	}
+	if err != nil {
+		headlessFail("Unable to save pattern: %v", err)
+	}
+}
+
+// simulate steps u for generations, writing the stats of every generation
+// when stats isn't nil and stopping at the first cycle detector sees when
+// it isn't nil
+func simulate(u life.Universe, generations int, stats statsWriter, detector *life.Detector) (*life.Cycle, error) {
+	if stats == nil && detector == nil {
+		// Hashlife can jump straight to the last generation
+		u.StepN(generations)
+		return nil, nil
+	}
+	var cycle *life.Cycle
+	for gen := 0; ; gen++ {
+		if stats != nil {
+			if err := stats.Write(u.Stats()); err != nil {
+				return nil, err
+			}
+		}
+		if detector != nil {
+			if c, ok := detector.Observe(u); ok {
+				cycle = &c
+				break
+			}
+		}
+		if gen == generations {
+			break
+		}
+		u.Step()
+	}
+	if stats != nil {
+		return cycle, stats.Flush()
+	}
+	return cycle, nil
+}
+
+func headlessFail(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, "CGL: "+format+"\n", a...)
+	os.Exit(-1)
+}
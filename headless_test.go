@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+func TestSimulate(t *testing.T) {
+	const blinker = "x = 3, y = 1\n3o!"
+	tests := []struct {
+		name        string
+		rle         string
+		generations int
+		// Stats format, "" for none
+		stats  string
+		stable bool
+		want   string
+		// Cycle found and the generation it stopped at
+		cycle *life.Cycle
+		gen   int
+	}{
+		{
+			name: "csv", rle: blinker, generations: 2, stats: "csv",
+			want: "generation,population,x,y,width,height,births,deaths\n" +
+				"0,3,1,2,3,1,0,0\n" +
+				"1,3,2,1,1,3,2,2\n" +
+				"2,3,1,2,3,1,2,2\n",
+			gen: 2,
+		},
+		{
+			name: "json", rle: blinker, generations: 1, stats: "json",
+			want: `{"generation":0,"population":3,"x":1,"y":2,"width":3,"height":1,"births":0,"deaths":0}` + "\n" +
+				`{"generation":1,"population":3,"x":2,"y":1,"width":1,"height":3,"births":2,"deaths":2}` + "\n",
+			gen: 1,
+		},
+		{
+			name: "no stats", rle: blinker, generations: 7,
+			gen: 7,
+		},
+		{
+			name: "stop when stable", rle: blinker, generations: 100, stats: "csv", stable: true,
+			want: "generation,population,x,y,width,height,births,deaths\n" +
+				"0,3,1,2,3,1,0,0\n" +
+				"1,3,2,1,1,3,2,2\n" +
+				"2,3,1,2,3,1,2,2\n",
+			cycle: &life.Cycle{Generation: 0, Period: 2}, gen: 2,
+		},
+		{
+			name: "still life", rle: "x = 2, y = 2\n2o$2o!", generations: 100, stable: true,
+			cycle: &life.Cycle{Generation: 0, Period: 1}, gen: 1,
+		},
+		{
+			name: "dies out", rle: "x = 2, y = 1\n2o!", generations: 100, stable: true,
+			cycle: &life.Cycle{Generation: 1}, gen: 1,
+		},
+		{
+			// A glider comes back around a 5x5 torus every 20 generations
+			name: "not stable yet", rle: "x = 3, y = 3\nbo$2bo$3o!", generations: 19, stable: true,
+			gen: 19,
+		},
+	}
+	for _, tt := range tests {
+		p, err := life.ParseRLE(strings.NewReader(tt.rle))
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := life.NewGrid(5, 5, life.CONWAY)
+		g.PlacePattern(p, 2, 1)
+		var out strings.Builder
+		var stats statsWriter
+		if tt.stats != "" {
+			if stats, err = newStatsWriter(tt.stats, &out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var detector *life.Detector
+		if tt.stable {
+			detector = life.NewDetector()
+		}
+		cycle, err := simulate(g, tt.generations, stats, detector)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if out.String() != tt.want {
+			t.Errorf("%s: wrote\n%s\nwant\n%s", tt.name, out.String(), tt.want)
+		}
+		if (cycle == nil) != (tt.cycle == nil) || cycle != nil && *cycle != *tt.cycle {
+			t.Errorf("%s: cycle %v, want %v", tt.name, cycle, tt.cycle)
+		}
+		if g.Generation() != tt.gen {
+			t.Errorf("%s: stopped at generation %d, want %d", tt.name, g.Generation(), tt.gen)
+		}
+	}
+}
+
+func TestNewStatsWriterFormat(t *testing.T) {
+	if _, err := newStatsWriter("xml", &strings.Builder{}); err == nil || !strings.Contains(err.Error(), `unknown stats format "xml"`) {
+		t.Errorf("error %v, want an unknown format", err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Changed cells kept for undo and redo together, about 24MB
+const MAX_UNDO_CELLS = 1 << 20
+
+// change is a cell an edit took from one state to another, dying states of
+// a Generations rule included
+type change struct {
+	life.Point
+	From, To uint8
+}
+
+// state returns the state of the cell at row, col of u
+func state(u life.Universe, row, col int) uint8 {
+	return u.RegionStates(row, col, 1, 1)[0][0]
+}
+
+// setState puts the cell at row, col of u in state
+func setState(u life.Universe, row, col int, state uint8) {
+	if state <= 1 {
+		u.SetCell(row, col, state == 1)
+		return
+	}
+	dying := life.NewPattern(1, 1)
+	dying.Dying = []life.DyingCell{{State: state}}
+	u.PlacePattern(dying, row, col)
+}
+
+// edit is everything changed by one stroke, fill, load or clear
+type edit struct {
+	changes []change
+	// The edit cleared the map and reset the generation from generation
+	cleared    bool
+	generation int
+}
+
+// History is the undo and redo stacks of the map editor
+type History struct {
+	undo []edit
+	redo []edit
+	// Edit being recorded, nil outside of Begin/End
+	current edit
+	open    bool
+	// Changes held in undo and redo
+	cells int
+}
+
+// Begin starts recording an edit, one still open is committed first
+func (h *History) Begin() {
+	h.End()
+	h.open = true
+}
+
+// End commits the edit being recorded, empty edits are dropped
+func (h *History) End() {
+	if !h.open {
+		return
+	}
+	h.open = false
+	if len(h.current.changes) == 0 && !h.current.cleared {
+		return
+	}
+	for _, e := range h.redo {
+		h.cells -= len(e.changes)
+	}
+	h.redo = nil
+	h.undo = append(h.undo, h.current)
+	h.cells += len(h.current.changes)
+	h.current = edit{}
+	// The newest edit is always kept, however large, so a big clear can be
+	// taken back
+	for h.cells > MAX_UNDO_CELLS && len(h.undo) > 1 {
+		h.cells -= len(h.undo[0].changes)
+		h.undo[0] = edit{}
+		h.undo = h.undo[1:]
+	}
+}
+
+// Edited reports whether the map was changed since the last Reset, not
+// counting edits that were undone
+func (h *History) Edited() bool {
+	return len(h.undo) > 0 || len(h.current.changes) > 0 || h.current.cleared
+}
+
+// Reset forgets every edit, used once the map has been stepped and old
+// edits no longer apply to it
+func (h *History) Reset() {
+	*h = History{}
+}
+
+func (h *History) record(c change) {
+	if h.open {
+		h.current.changes = append(h.current.changes, c)
+	}
+}
+
+// Undo reverts the last edit on u and returns how many cells it changed
+func (h *History) Undo(u life.Universe) int {
+	h.End()
+	if len(h.undo) == 0 {
+		return 0
+	}
+	e := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	for i := len(e.changes) - 1; i >= 0; i-- {
+		c := e.changes[i]
+		setState(u, c.Row, c.Col, c.From)
+	}
+	if e.cleared {
+		u.SetGeneration(e.generation)
+	}
+	h.redo = append(h.redo, e)
+	return len(e.changes)
+}
+
+// Redo applies the last undone edit to u again and returns how many cells
+// it changed
+func (h *History) Redo(u life.Universe) int {
+	h.End()
+	if len(h.redo) == 0 {
+		return 0
+	}
+	e := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	for _, c := range e.changes {
+		setState(u, c.Row, c.Col, c.To)
+	}
+	if e.cleared {
+		u.SetGeneration(0)
+	}
+	h.undo = append(h.undo, e)
+	return len(e.changes)
+}
+
+// recorder writes to a universe, noting every cell that changes in the
+// history
+type recorder struct {
+	life.Universe
+	h *History
+}
+
+func (r recorder) SetCell(row, col int, alive bool) {
+	if !r.h.open {
+		r.Universe.SetCell(row, col, alive)
+		return
+	}
+	c := change{Point: life.Point{Row: row, Col: col}, From: state(r.Universe, row, col)}
+	r.Universe.SetCell(row, col, alive)
+	// Cells off a finite map don't change
+	if c.To = state(r.Universe, row, col); c.To != c.From {
+		r.h.record(c)
+	}
+}
+
+func (r recorder) PlacePattern(p *life.Pattern, row, col int) {
+	if !r.h.open {
+		r.Universe.PlacePattern(p, row, col)
+		return
+	}
+	changes := make([]change, 0, len(p.Cells)+len(p.Dying))
+	for _, c := range p.Cells {
+		changes = append(changes, change{Point: life.Point{Row: row + c.Row, Col: col + c.Col}})
+	}
+	for _, c := range p.Dying {
+		changes = append(changes, change{Point: life.Point{Row: row + c.Row, Col: col + c.Col}})
+	}
+	for i := range changes {
+		changes[i].From = state(r.Universe, changes[i].Row, changes[i].Col)
+	}
+	r.Universe.PlacePattern(p, row, col)
+	// Cells that fell off a finite map didn't change
+	for _, c := range changes {
+		if c.To = state(r.Universe, c.Row, c.Col); c.To != c.From {
+			r.h.record(c)
+		}
+	}
+}
+
+func (r recorder) Clear() {
+	if r.h.open {
+		st := r.Universe.Stats()
+		for i, row := range r.Universe.RegionStates(st.Y, st.X, st.Height, st.Width) {
+			for j, state := range row {
+				if state == 0 {
+					continue
+				}
+				r.h.record(change{Point: life.Point{Row: st.Y + i, Col: st.X + j}, From: state})
+			}
+		}
+		r.h.current.cleared = true
+		r.h.current.generation = st.Generation
+	}
+	r.Universe.Clear()
+}
+
+// edits returns the engine with changes recorded in the undo history
+func (m *Model) edits() recorder {
+	return recorder{m.GameEngine.Universe, &m.History}
+}
+
+// clear kills every cell as a single undoable edit
+func (m *Model) clear() {
+	m.History.Begin()
+	m.edits().Clear()
+	m.History.End()
+	m.filled = nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// states returns every cell state of a grid
+func states(g *life.Grid) [][]uint8 {
+	height, width := g.Size()
+	return g.RegionStates(0, 0, height, width)
+}
+
+func sameStates(t *testing.T, what string, got, want [][]uint8) {
+	t.Helper()
+	for r := range want {
+		if !slices.Equal(got[r], want[r]) {
+			t.Fatalf("%s: row %d is %v, want %v", what, r, got[r], want[r])
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	brain := life.MustParseRule("/2/3")
+	p, err := life.ParseRLE(strings.NewReader("x = 4, y = 2, rule = /2/3\nA.B$2B.A!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		edit func(r recorder)
+		// Cells the edit changed
+		changed int
+	}{
+		{"stroke over dying cells", func(r recorder) {
+			for c := range 5 {
+				r.SetCell(1, c, true)
+			}
+		}, 4},
+		{"erasing dying cells", func(r recorder) {
+			for c := range 6 {
+				r.SetCell(2, c, false)
+			}
+		}, 4},
+		{"pattern over dying cells", func(r recorder) {
+			r.PlacePattern(p, 2, 1)
+		}, 5},
+		{"off the edges", func(r recorder) {
+			r.SetCell(-1, 0, true)
+			r.SetCell(0, 8, true)
+			r.SetCell(6, 6, true)
+			r.PlacePattern(p, 4, 6)
+		}, 3},
+		{"clear", func(r recorder) {
+			r.Clear()
+		}, 9},
+	}
+	for _, tt := range tests {
+		g := life.NewBoundedGrid(6, 8, brain)
+		g.PlacePattern(p, 1, 0)
+		g.PlacePattern(p, 2, 3)
+		g.SetGeneration(7)
+		before := states(g)
+		var h History
+		h.Begin()
+		tt.edit(recorder{g, &h})
+		h.End()
+		after, gen := states(g), g.Generation()
+		if n := h.Undo(g); n != tt.changed {
+			t.Errorf("%s: undid %d cells, want %d", tt.name, n, tt.changed)
+		}
+		sameStates(t, tt.name+", undone", states(g), before)
+		if g.Generation() != 7 {
+			t.Errorf("%s: undone at generation %d, want 7", tt.name, g.Generation())
+		}
+		if n := h.Redo(g); n != tt.changed {
+			t.Errorf("%s: redid %d cells, want %d", tt.name, n, tt.changed)
+		}
+		sameStates(t, tt.name+", redone", states(g), after)
+		if g.Generation() != gen {
+			t.Errorf("%s: redone at generation %d, want %d", tt.name, g.Generation(), gen)
+		}
+	}
+}
+
+func TestHistoryNoChange(t *testing.T) {
+	// Edits that change nothing leave nothing to undo
+	g := life.NewBoundedGrid(4, 4, life.CONWAY)
+	g.SetCell(1, 1, true)
+	var h History
+	h.Begin()
+	r := recorder{g, &h}
+	r.SetCell(1, 1, true)
+	r.SetCell(2, 2, false)
+	r.SetCell(-1, 2, true)
+	r.SetCell(2, 4, true)
+	h.End()
+	if h.Edited() || h.Undo(g) != 0 {
+		t.Fatal("recorded edits that changed nothing")
+	}
+}
+
+// fakeEdit records an edit of n cells without a universe
+func fakeEdit(h *History, n int) {
+	h.Begin()
+	for i := range n {
+		h.record(change{Point: life.Point{Row: i}, To: 1})
+	}
+	h.End()
+}
+
+func TestHistoryBudget(t *testing.T) {
+	var h History
+	for range 3 {
+		fakeEdit(&h, 100)
+	}
+	if len(h.undo) != 3 || h.cells != 300 {
+		t.Fatalf("%d edits of %d cells, want 3 of 300", len(h.undo), h.cells)
+	}
+	// Older edits are dropped to stay under the budget
+	fakeEdit(&h, MAX_UNDO_CELLS-250)
+	if len(h.undo) != 3 || h.cells != MAX_UNDO_CELLS-50 {
+		t.Fatalf("%d edits of %d cells, want 3 of %d", len(h.undo), h.cells, MAX_UNDO_CELLS-50)
+	}
+	// But never the newest one
+	fakeEdit(&h, MAX_UNDO_CELLS+1)
+	if len(h.undo) != 1 || h.cells != MAX_UNDO_CELLS+1 {
+		t.Fatalf("%d edits of %d cells, want 1 of %d", len(h.undo), h.cells, MAX_UNDO_CELLS+1)
+	}
+}
+
+func TestHistoryRedo(t *testing.T) {
+	u, err := life.NewSparse(life.CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h History
+	r := recorder{u, &h}
+	for i := range 3 {
+		h.Begin()
+		r.SetCell(i, i, true)
+		h.End()
+	}
+	h.Undo(u)
+	h.Undo(u)
+	if h.cells != 3 {
+		t.Fatalf("%d cells held, want 3", h.cells)
+	}
+	// A new edit drops what was undone
+	h.Begin()
+	r.SetCell(5, 5, true)
+	h.End()
+	if n := h.Redo(u); n != 0 {
+		t.Fatalf("redid %d cells after a new edit", n)
+	}
+	if h.cells != 2 {
+		t.Fatalf("%d cells held, want 2", h.cells)
+	}
+	if h.Undo(u) != 1 || h.Undo(u) != 1 || h.Undo(u) != 0 {
+		t.Fatal("undid other edits than the new one and the first one")
+	}
+	if u.Cell(0, 0) || u.Cell(5, 5) {
+		t.Fatal("cells left after undoing every edit")
+	}
+}
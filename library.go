@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ncanvas "github.com/NimbleMarkets/ntcharts/canvas"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// patternItem is an object of the pattern library
+type patternItem struct {
+	*life.Pattern
+	// Built in, or the file it was loaded from
+	source string
+}
+
+func (i patternItem) FilterValue() string { return i.Name }
+
+// libraryDir returns where users keep their own patterns
+func libraryDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cgl", "patterns")
+}
+
+// loadLibrary returns the built in catalog followed by the patterns in dir,
+// files that fail to load are skipped and reported in the error
+func loadLibrary(dir string) ([]list.Item, error) {
+	var items []list.Item
+	for _, p := range life.Catalog() {
+		items = append(items, patternItem{p, "built in"})
+	}
+	if dir == "" {
+		return items, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return items, nil
+	} else if err != nil {
+		return items, err
+	}
+	var failed []string
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || ext != ".rle" && ext != ".cells" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		p, err := life.LoadPattern(path)
+		if err != nil {
+			failed = append(failed, e.Name())
+			continue
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		}
+		items = append(items, patternItem{p, path})
+	}
+	if len(failed) > 0 {
+		return items, fmt.Errorf("couldn't load %s", strings.Join(failed, ", "))
+	}
+	return items, nil
+}
+
+func newLibraryList(width int) list.Model {
+	l := list.New(nil, itemDelegate{}, width, 5)
+	l.Title = "PATTERN LIBRARY  /: filter  ENTER: pick  ESC: back"
+	l.SetShowHelp(false)
+	l.SetShowStatusBar(false)
+	l.DisableQuitKeybindings()
+	l.Styles.Title = lipgloss.NewStyle().Foreground(purple)
+	l.Styles.TitleBar = lipgloss.NewStyle().PaddingLeft(2)
+	l.Styles.PaginationStyle = paginationStyle
+	return l
+}
+
+// openLibrary rescans the user's patterns and shows the library
+func (m *Model) openLibrary() {
+	items, err := loadLibrary(libraryDir())
+	if err != nil {
+		m.Status = fmt.Sprintf("Pattern library: %v", err)
+	}
+	m.LibraryList.SetItems(items)
+	m.GameState = Browsing
+}
+
+// renderPreview draws the selected library pattern in place of the map,
+// shrunk with the zoom levels if it doesn't fit
+func (m *Model) renderPreview() string {
+	canvas := ncanvas.New(m.Width, m.mapHeight())
+	canvas.Fill(ncanvas.NewCell(' '))
+	it, ok := m.LibraryList.SelectedItem().(patternItem)
+	if !ok {
+		canvas.SetStringWithStyle(image.Point{0, 0}, "No patterns match", colors[2])
+		return canvas.View()
+	}
+	lines := []string{fmt.Sprintf("%s  %dx%d, %d cells  (%s)", it.Name, it.Width, it.Height, it.Population(), it.source)}
+	lines = append(lines, it.Comments...)
+	lines = lines[:min(len(lines), 3)]
+	for i, line := range lines {
+		canvas.SetStringWithStyle(image.Point{0, i}, line, colors[2])
+	}
+
+	top := len(lines) + 1
+	rows, cols := max(canvas.Height()-top, 1), m.Width
+	zoom := 0
+	for zoom < len(ZOOMS)-1 && (it.Height > rows*ZOOMS[zoom].Rows || it.Width > cols*ZOOMS[zoom].Cols) {
+		zoom++
+	}
+	z := ZOOMS[zoom]
+	cells := make([][]bool, rows*z.Rows)
+	for i := range cells {
+		cells[i] = make([]bool, cols*z.Cols)
+	}
+	// Centered, what still doesn't fit at the last zoom level is cut off
+	offRow, offCol := (len(cells)-it.Height)/2, (cols*z.Cols-it.Width)/2
+	for _, c := range it.Cells {
+		r, col := c.Row+offRow, c.Col+offCol
+		if r >= 0 && r < len(cells) && col >= 0 && col < len(cells[r]) {
+			cells[r][col] = true
+		}
+	}
+	for y := range rows {
+		for x := range cols {
+			if r := glyph(cells, y*z.Rows, x*z.Cols, z.Rows, z.Cols); r != ' ' {
+				canvas.SetRuneWithStyle(image.Point{x, top + y}, r, colors[0])
+			}
+		}
+	}
+	return canvas.View()
+}
+
+func (m *Model) updateLibrary(msg tea.KeyMsg) tea.Cmd {
+	if m.LibraryList.FilterState() == list.Unfiltered {
+		switch msg.Type {
+		case tea.KeyCtrlC, tea.KeyEsc:
+			m.GameState = Mapping
+			return nil
+		}
+	}
+	if m.LibraryList.FilterState() != list.Filtering && msg.Type == tea.KeyEnter {
+		m.GameState = Mapping
+		if it, ok := m.LibraryList.SelectedItem().(patternItem); ok {
+			if it.Rule != nil && *it.Rule != m.GameEngine.Rule() {
+				m.Status = fmt.Sprintf("%s is made for %s", it.Name, it.Rule)
+			}
+			return m.pickStamp(it.Pattern)
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.LibraryList, cmd = m.LibraryList.Update(msg)
+	return cmd
+}
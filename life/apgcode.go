@@ -0,0 +1,88 @@
+package life
+
+import (
+	"strings"
+)
+
+// Digits of the extended Wechsler format, a column of a 5 row strip is the
+// digit of its cells as bits with the top cell as the lowest
+const wechslerDigits = "0123456789abcdefghijklmnopqrstuv"
+
+// Counts of 4 to 39 zeros after a y
+const zeroRuns = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// Wechsler encodes the live cells of p in extended Wechsler format, the
+// part of an apgcode after the underscore, e.g. "153" for a glider
+func Wechsler(p *Pattern) string {
+	var sb strings.Builder
+	for top := 0; top < p.Height; top += 5 {
+		if top > 0 {
+			sb.WriteByte('z')
+		}
+		strip := make([]byte, p.Width)
+		for i := range strip {
+			strip[i] = '0'
+		}
+		for _, c := range p.Cells {
+			if c.Row >= top && c.Row < top+5 {
+				digit := strings.IndexByte(wechslerDigits, strip[c.Col]) | 1<<(c.Row-top)
+				strip[c.Col] = wechslerDigits[digit]
+			}
+		}
+		writeStrip(&sb, strings.TrimRight(string(strip), "0"))
+	}
+	return sb.String()
+}
+
+// writeStrip writes a strip shortening runs of zeros: w is 2 of them, x 3
+// and y followed by a digit of zeroRuns 4 or more
+func writeStrip(sb *strings.Builder, strip string) {
+	for i := 0; i < len(strip); {
+		if strip[i] != '0' {
+			sb.WriteByte(strip[i])
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(strip) && strip[i+run] == '0' {
+			run++
+		}
+		i += run
+		for run > 0 {
+			switch n := min(run, 3+len(zeroRuns)); {
+			case n >= 4:
+				sb.WriteByte('y')
+				sb.WriteByte(zeroRuns[n-4])
+				run -= n
+			case n == 3:
+				sb.WriteByte('x')
+				run -= 3
+			case n == 2:
+				sb.WriteByte('w')
+				run -= 2
+			default:
+				sb.WriteByte('0')
+				run--
+			}
+		}
+	}
+}
+
+// canonical returns the Wechsler code of the phases of an object that
+// comes first in any orientation: the shortest, then the lowest
+func canonical(phases []*Pattern) string {
+	var best string
+	for _, p := range phases {
+		for range 2 {
+			for range 4 {
+				code := Wechsler(p)
+				if best == "" || len(code) < len(best) || len(code) == len(best) && code < best {
+					best = code
+				}
+				p = p.Rotate()
+			}
+			p = p.Mirror()
+		}
+	}
+	return best
+}
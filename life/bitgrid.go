@@ -0,0 +1,424 @@
+package life
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Fewest words of the map a worker steps, smaller maps aren't worth
+// splitting into bands
+const BAND_WORDS = 2048
+
+// BitGrid is a fixed size map like Grid for Life-like rules, built for maps
+// of millions of cells. Rows are packed 64 cells to a word and stepped with
+// bitwise neighbor counts into a second buffer, in bands of rows spread over
+// GOMAXPROCS workers. It is safe for concurrent use.
+type BitGrid struct {
+	mu sync.Mutex
+	// Row r is words [r*stride, (r+1)*stride), column c bit c%64 of word
+	// c/64. Bits past the width are always 0.
+	cells []uint64
+	// Buffer the next generation is stepped into
+	next   []uint64
+	stride int
+	rule   Rule
+	// Cells past the edges are dead instead of wrapping around
+	bounded bool
+	// Bands stepped at once
+	workers    int
+	generation int
+	births     int
+	deaths     int
+	height     int
+	width      int
+}
+
+// NewBitGrid returns an empty height x width map whose edges wrap around
+func NewBitGrid(height, width int, rule Rule) (*BitGrid, error) {
+	if rule.States > 2 {
+		return nil, ErrGenerations
+	}
+	b := &BitGrid{rule: rule, workers: runtime.GOMAXPROCS(0)}
+	b.alloc(height, width)
+	return b, nil
+}
+
+// NewBoundedBitGrid returns an empty height x width map whose edges don't
+// wrap, everything past them stays dead
+func NewBoundedBitGrid(height, width int, rule Rule) (*BitGrid, error) {
+	b, err := NewBitGrid(height, width, rule)
+	if err != nil {
+		return nil, err
+	}
+	b.bounded = true
+	return b, nil
+}
+
+func (b *BitGrid) alloc(height, width int) {
+	b.height, b.width = height, width
+	b.stride = (width + 63) / 64
+	b.cells = make([]uint64, height*b.stride)
+	b.next = make([]uint64, height*b.stride)
+}
+
+// SetWorkers sets how many bands of rows are stepped in parallel, at least 1
+func (b *BitGrid) SetWorkers(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.workers = max(n, 1)
+}
+
+func (b *BitGrid) Topology() Topology {
+	if b.bounded {
+		return Bounded
+	}
+	return Torus
+}
+
+// counter adds up to 8 neighbor bitboards, bit i of n1, n2 and n4 hold the
+// count of cell i in binary and n8 is set when all 8 are alive
+type counter struct {
+	n1, n2, n4, n8 uint64
+}
+
+func (c *counter) add(x uint64) {
+	carry := c.n1 & x
+	c.n1 ^= x
+	carry, c.n2 = c.n2&carry, c.n2^carry
+	carry, c.n4 = c.n4&carry, c.n4^carry
+	c.n8 |= carry
+}
+
+// match returns the cells whose count is one of counts
+func (c *counter) match(counts *[9]bool) uint64 {
+	var m uint64
+	for n, ok := range counts[:8] {
+		if !ok {
+			continue
+		}
+		eq := ^c.n8
+		for bit, x := range [3]uint64{c.n1, c.n2, c.n4} {
+			if n&(1<<bit) == 0 {
+				x = ^x
+			}
+			eq &= x
+		}
+		m |= eq
+	}
+	if counts[8] {
+		m |= c.n8
+	}
+	return m
+}
+
+// row returns row r of buf, wrapping around on a torus. Rows past the edges
+// of a bounded map are nil.
+func (b *BitGrid) row(buf []uint64, r int) []uint64 {
+	if r < 0 || r >= b.height {
+		if b.bounded {
+			return nil
+		}
+		r = (r + b.height) % b.height
+	}
+	return buf[r*b.stride : (r+1)*b.stride]
+}
+
+// west returns word k of row shifted so every cell holds its west neighbor
+func (b *BitGrid) west(row []uint64, k int) uint64 {
+	var carry uint64
+	if k > 0 {
+		carry = row[k-1] >> 63
+	} else if !b.bounded {
+		carry = row[b.stride-1] >> ((b.width - 1) % 64) & 1
+	}
+	return row[k]<<1 | carry
+}
+
+// east returns word k of row shifted so every cell holds its east neighbor
+func (b *BitGrid) east(row []uint64, k int) uint64 {
+	var carry uint64
+	if k < b.stride-1 {
+		carry = row[k+1] << 63
+	} else if !b.bounded {
+		carry = (row[0] & 1) << ((b.width - 1) % 64)
+	}
+	return row[k]>>1 | carry
+}
+
+// stepRows steps rows [from, to) into b.next
+func (b *BitGrid) stepRows(from, to int) (births, deaths int) {
+	// Bits past the width in the last word of a row
+	pad := ^uint64(0) >> ((64 - b.width%64) % 64)
+	for r := from; r < to; r++ {
+		up, mid, down := b.row(b.cells, r-1), b.row(b.cells, r), b.row(b.cells, r+1)
+		out := b.row(b.next, r)
+		for k, alive := range mid {
+			var c counter
+			for _, row := range [2][]uint64{up, down} {
+				if row != nil {
+					c.add(b.west(row, k))
+					c.add(row[k])
+					c.add(b.east(row, k))
+				}
+			}
+			c.add(b.west(mid, k))
+			c.add(b.east(mid, k))
+			next := alive&c.match(&b.rule.Survive) | ^alive&c.match(&b.rule.Birth)
+			if k == b.stride-1 {
+				next &= pad
+			}
+			out[k] = next
+			births += bits.OnesCount64(next &^ alive)
+			deaths += bits.OnesCount64(alive &^ next)
+		}
+	}
+	return births, deaths
+}
+
+// Step advances the map by one generation
+func (b *BitGrid) Step() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bands := max(min(b.workers, len(b.cells)/BAND_WORDS, b.height), 1)
+	if bands == 1 {
+		b.births, b.deaths = b.stepRows(0, b.height)
+	} else {
+		births, deaths := make([]int, bands), make([]int, bands)
+		var wg sync.WaitGroup
+		for i := range bands {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				births[i], deaths[i] = b.stepRows(b.height*i/bands, b.height*(i+1)/bands)
+			}()
+		}
+		wg.Wait()
+		b.births, b.deaths = 0, 0
+		for i := range bands {
+			b.births += births[i]
+			b.deaths += deaths[i]
+		}
+	}
+	b.cells, b.next = b.next, b.cells
+	b.generation++
+}
+
+// StepN advances the map by n generations
+func (b *BitGrid) StepN(n int) {
+	for range n {
+		b.Step()
+	}
+}
+
+func (b *BitGrid) get(row, col int) bool {
+	return b.cells[row*b.stride+col/64]>>(col%64)&1 == 1
+}
+
+func (b *BitGrid) set(row, col int, alive bool) {
+	word, bit := &b.cells[row*b.stride+col/64], uint64(1)<<(col%64)
+	if alive {
+		*word |= bit
+	} else {
+		*word &^= bit
+	}
+}
+
+// PlacePattern sets the live cells of p with its top left corner at (x, y),
+// cells that fall off the board are dropped
+func (b *BitGrid) PlacePattern(p *Pattern, x, y int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range p.Cells {
+		row, col := x+c.Row, y+c.Col
+		if row >= 0 && row < b.height && col >= 0 && col < b.width {
+			b.set(row, col, true)
+		}
+	}
+}
+
+// Pattern returns the live cells of the map cropped to their bounding box,
+// tagged with the active rule
+func (b *BitGrid) Pattern() *Pattern {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	top, left, height, width, _ := b.bounds()
+	rule := b.rule
+	p := NewPattern(height, width)
+	p.Rule = &rule
+	b.each(func(row, col int) {
+		p.Cells = append(p.Cells, Point{row - top, col - left})
+	})
+	return p
+}
+
+// each calls f with every live cell, row by row
+func (b *BitGrid) each(f func(row, col int)) {
+	for i, word := range b.cells {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			f(i/b.stride, i%b.stride*64+bit)
+			word &= word - 1
+		}
+	}
+}
+
+// bounds returns the bounding box of the live cells and the population, the
+// box is empty when there are no live cells
+func (b *BitGrid) bounds() (top, left, height, width, population int) {
+	top, left, bottom, right := b.height, b.width, -1, -1
+	for i, word := range b.cells {
+		if word == 0 {
+			continue
+		}
+		row, col := i/b.stride, i%b.stride*64
+		top, bottom = min(top, row), max(bottom, row)
+		left = min(left, col+bits.TrailingZeros64(word))
+		right = max(right, col+63-bits.LeadingZeros64(word))
+		population += bits.OnesCount64(word)
+	}
+	if bottom < 0 {
+		return 0, 0, 0, 0, 0
+	}
+	return top, left, bottom - top + 1, right - left + 1, population
+}
+
+// Stats summarizes the current generation
+func (b *BitGrid) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	top, left, height, width, population := b.bounds()
+	return Stats{
+		Generation: b.generation,
+		Population: population,
+		X:          left,
+		Y:          top,
+		Width:      width,
+		Height:     height,
+		Births:     b.births,
+		Deaths:     b.deaths,
+	}
+}
+
+func (b *BitGrid) Hash() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	colPow := make([]uint64, b.width)
+	pow := uint64(1)
+	for j := range colPow {
+		colPow[j] = pow
+		pow *= hashCol
+	}
+	var sum uint64
+	rowPow, lastRow := uint64(1), 0
+	b.each(func(row, col int) {
+		for ; lastRow < row; lastRow++ {
+			rowPow *= hashRow
+		}
+		sum += rowPow * colPow[col]
+	})
+	return sum
+}
+
+// Clear kills every cell and resets the generation count
+func (b *BitGrid) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.generation = 0
+	b.births, b.deaths = 0, 0
+	clear(b.cells)
+}
+
+// Resize grows the map to at least height x width, it never shrinks
+func (b *BitGrid) Resize(height, width int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if height <= b.height && width <= b.width {
+		return
+	}
+	cells, stride := b.cells, b.stride
+	b.alloc(max(height, b.height), max(width, b.width))
+	for i := range len(cells) / max(stride, 1) {
+		copy(b.cells[i*b.stride:], cells[i*stride:(i+1)*stride])
+	}
+}
+
+// SetCell sets the cell at row x, column y, cells off the map are ignored
+func (b *BitGrid) SetCell(x, y int, alive bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if x < 0 || x >= b.height || y < 0 || y >= b.width {
+		return
+	}
+	b.set(x, y, alive)
+}
+
+// Cell reports whether the cell at row x, column y is alive, cells off the
+// map are dead
+func (b *BitGrid) Cell(x, y int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if x < 0 || x >= b.height || y < 0 || y >= b.width {
+		return false
+	}
+	return b.get(x, y)
+}
+
+func (b *BitGrid) Region(top, left, height, width int) [][]bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cells := newRegion(height, width)
+	for i := max(top, 0); i < min(top+height, b.height); i++ {
+		for j := max(left, 0); j < min(left+width, b.width); j++ {
+			cells[i-top][j-left] = b.get(i, j)
+		}
+	}
+	return cells
+}
+
+func (b *BitGrid) RegionStates(top, left, height, width int) [][]uint8 {
+	cells := newStates(height, width)
+	for i, row := range b.Region(top, left, height, width) {
+		for j, alive := range row {
+			if alive {
+				cells[i][j] = 1
+			}
+		}
+	}
+	return cells
+}
+
+func (b *BitGrid) Size() (height, width int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.height, b.width
+}
+
+func (b *BitGrid) Generation() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.generation
+}
+
+func (b *BitGrid) SetGeneration(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.generation = n
+}
+
+// SetRule switches to rule, Generations rules aren't supported
+func (b *BitGrid) SetRule(rule Rule) error {
+	if rule.States > 2 {
+		return ErrGenerations
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rule = rule
+	return nil
+}
+
+func (b *BitGrid) Rule() Rule {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rule
+}
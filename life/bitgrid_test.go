@@ -0,0 +1,130 @@
+package life
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// soup fills the height x width region at the origin of u with a random
+// soup, the same one for every seed
+func soup(u Universe, height, width int, seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	for i := range height {
+		for j := range width {
+			u.SetCell(i, j, rng.Intn(8) == 0)
+		}
+	}
+}
+
+func TestBitGridStep(t *testing.T) {
+	// Several workers even on a single core, so that maps of more than
+	// BAND_WORDS*2 words are stepped in bands
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	tests := []struct {
+		height, width int
+		// Soup density is 1/8, 0 places a glider over every corner instead
+		seed int64
+	}{
+		{7, 65, 0},
+		{9, 64, 0},
+		{33, 130, 1},
+		{64, 63, 2},
+		// 2 bands of rows
+		{300, 1000, 3},
+		{515, 517, 4},
+	}
+	glider := catalogPattern(t, "Glider")
+	for _, tt := range tests {
+		for _, bounded := range []bool{false, true} {
+			name := fmt.Sprintf("%dx%d bounded=%t", tt.width, tt.height, bounded)
+			var g *Grid
+			var b *BitGrid
+			var err error
+			if bounded {
+				g = NewBoundedGrid(tt.height, tt.width, CONWAY)
+				b, err = NewBoundedBitGrid(tt.height, tt.width, CONWAY)
+			} else {
+				g = NewGrid(tt.height, tt.width, CONWAY)
+				b, err = NewBitGrid(tt.height, tt.width, CONWAY)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.seed == 0 {
+				for _, corner := range [][2]int{{-1, -1}, {-1, tt.width - 2}, {tt.height - 2, -1}, {tt.height - 2, tt.width - 2}} {
+					g.PlacePattern(glider, corner[0], corner[1])
+					b.PlacePattern(glider, corner[0], corner[1])
+				}
+				// And one that crosses the word boundary at column 64
+				g.PlacePattern(glider, 1, 62)
+				b.PlacePattern(glider, 1, 62)
+			} else {
+				soup(g, tt.height, tt.width, tt.seed)
+				soup(b, tt.height, tt.width, tt.seed)
+			}
+			for range 60 {
+				g.Step()
+				b.Step()
+				if gs, bs := g.Stats(), b.Stats(); gs != bs {
+					t.Fatalf("%s: stats %+v, want %+v", name, bs, gs)
+				}
+				if g.Hash() != b.Hash() {
+					t.Fatalf("%s: cells differ at generation %d", name, g.Generation())
+				}
+			}
+			if gp, bp := g.Pattern(), b.Pattern(); !slices.Equal(gp.Cells, bp.Cells) {
+				t.Fatalf("%s: cells differ at generation %d", name, g.Generation())
+			}
+		}
+	}
+}
+
+// Map sizes the engines are benchmarked on, height x width
+var benchSizes = [][2]int{{132, 160}, {1000, 1000}, {4000, 4000}}
+
+// benchStep times a generation of the universe newUniverse returns, filled
+// with the same soup for every engine, size and worker count
+func benchStep(b *testing.B, height, width int, newUniverse func() Universe) {
+	u := newUniverse()
+	soup(u, height, width, 1)
+	b.ResetTimer()
+	for range b.N {
+		u.Step()
+	}
+	b.ReportMetric(float64(height*width)*float64(b.N)/b.Elapsed().Seconds(), "cells/s")
+}
+func BenchmarkGridStep(b *testing.B) {
+	for _, size := range benchSizes {
+		height, width := size[0], size[1]
+		// Grid steps on a single goroutine
+		b.Run(fmt.Sprintf("%dx%d/workers=1", width, height), func(b *testing.B) {
+			benchStep(b, height, width, func() Universe {
+				return NewGrid(height, width, CONWAY)
+			})
+		})
+	}
+}
+
+func BenchmarkBitGridStep(b *testing.B) {
+	for _, size := range benchSizes {
+		height, width := size[0], size[1]
+		for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+			b.Run(fmt.Sprintf("%dx%d/workers=%d", width, height, workers), func(b *testing.B) {
+				benchStep(b, height, width, func() Universe {
+					g, err := NewBitGrid(height, width, CONWAY)
+					if err != nil {
+						b.Fatal(err)
+					}
+					g.SetWorkers(workers)
+					return g
+				})
+			})
+			if runtime.GOMAXPROCS(0) == 1 {
+				break
+			}
+		}
+	}
+}
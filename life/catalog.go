@@ -0,0 +1,52 @@
+package life
+
+import (
+	"strings"
+)
+
+// Built in objects of the pattern library
+var catalogRLE = []string{
+	`#N Glider
+#C The smallest spaceship, travels diagonally at c/4
+x = 3, y = 3, rule = B3/S23
+bob$2bo$3o!`,
+	`#N LWSS
+#C Lightweight spaceship, travels orthogonally at c/2
+x = 5, y = 4, rule = B3/S23
+bo2bo$o4b$o3bo$4o!`,
+	`#N Pulsar
+#C Period 3 oscillator
+x = 13, y = 13, rule = B3/S23
+2b3o3b3o2b2$o4bobo4bo$o4bobo4bo$o4bobo4bo$2b3o3b3o2b2$2b3o3b3o2b$o4bob
+o4bo$o4bobo4bo$o4bobo4bo2$2b3o3b3o!`,
+	`#N Gosper glider gun
+#C The first known gun, fires a glider every 30 generations
+x = 36, y = 9, rule = B3/S23
+24bo$22bobo$12b2o6b2o12b2o$11bo3bo4b2o12b2o$2o8bo5bo3b2o$2o8bo3bob2o4bo
+bo$10bo5bo7bo$11bo3bo$12b2o!`,
+	`#N R-pentomino
+#C Methuselah, stabilizes after 1103 generations
+x = 3, y = 3, rule = B3/S23
+b2o$2o$bo!`,
+	`#N Acorn
+#C Methuselah, stabilizes after 5206 generations
+x = 7, y = 3, rule = B3/S23
+bo5b$3bo3b$2o2b3o!`,
+	`#N Diehard
+#C Methuselah, dies out after 130 generations
+x = 8, y = 3, rule = B3/S23
+6bob$2o6b$bo3b3o!`,
+}
+
+// Catalog returns the built in objects of the pattern library
+func Catalog() []*Pattern {
+	patterns := make([]*Pattern, len(catalogRLE))
+	for i, rle := range catalogRLE {
+		p, err := ParseRLE(strings.NewReader(rle))
+		if err != nil {
+			panic(err)
+		}
+		patterns[i] = p
+	}
+	return patterns
+}
@@ -0,0 +1,94 @@
+package life
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParsePlaintext reads a pattern in Plaintext (.cells) format:
+// https://conwaylife.com/wiki/Plaintext
+func ParsePlaintext(r io.Reader) (*Pattern, error) {
+	var name string
+	var comments []string
+	var rule *Rule
+	var rows [][]bool
+	width := 0
+	lineNo := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if strings.HasPrefix(line, "!") {
+			comment := strings.TrimSpace(line[1:])
+			switch {
+			case strings.HasPrefix(comment, "Name:"):
+				name = strings.TrimSpace(comment[len("Name:"):])
+			case strings.HasPrefix(comment, "Rule:"):
+				r, err := ParseRule(strings.TrimSpace(comment[len("Rule:"):]))
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				rule = &r
+			case comment != "":
+				comments = append(comments, comment)
+			}
+			continue
+		}
+		row := make([]bool, len(line))
+		for j, ch := range []byte(line) {
+			switch ch {
+			case '.':
+			case 'O', '*':
+				row[j] = true
+			default:
+				return nil, fmt.Errorf("line %d: unexpected character %q, cells must be '.' or 'O'", lineNo, ch)
+			}
+		}
+		width = max(width, len(row))
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	p := NewPattern(len(rows), width)
+	p.Name = name
+	p.Comments = comments
+	p.Rule = rule
+	for i, row := range rows {
+		for j, alive := range row {
+			if alive {
+				p.Cells = append(p.Cells, Point{i, j})
+			}
+		}
+	}
+	return p, nil
+}
+
+// WritePlaintext writes p in Plaintext (.cells) format. The format has no
+// rule field, so the rule is recorded in a "!Rule:" comment.
+func WritePlaintext(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	if p.Name != "" {
+		fmt.Fprintf(bw, "!Name: %s\n", p.Name)
+	}
+	if p.Rule != nil {
+		fmt.Fprintf(bw, "!Rule: %s\n", p.Rule)
+	}
+	for _, c := range p.Comments {
+		fmt.Fprintf(bw, "!%s\n", c)
+	}
+	cells := p.Cells
+	for i := range p.Height {
+		col := 0
+		for len(cells) > 0 && cells[0].Row == i {
+			bw.WriteString(strings.Repeat(".", cells[0].Col-col))
+			bw.WriteByte('O')
+			col = cells[0].Col + 1
+			cells = cells[1:]
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,185 @@
+package life
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Longest period an object or a stabilized soup can have and still be
+// recognized
+const MAX_PERIOD = 120
+
+// Kind tells what an object does when left alone
+type Kind int
+
+const (
+	// Didn't repeat within MAX_PERIOD generations
+	Unknown Kind = iota
+	StillLife
+	Oscillator
+	Spaceship
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Unknown:
+		return "unknown"
+	case StillLife:
+		return "still life"
+	case Oscillator:
+		return "oscillator"
+	case Spaceship:
+		return "spaceship"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Object is a cluster of cells that repeats by itself
+type Object struct {
+	// apgcode, e.g. xs4_33 for a block or xq4_153 for a glider. The same
+	// object has the same code in any phase, orientation and position.
+	Code string
+	// Well known name under B3/S23, "" if it has none
+	Name   string
+	Kind   Kind
+	Period int
+	// Live cells on the board
+	Cells []Point
+}
+
+// Classify runs cells alone under rule until they repeat, up to MAX_PERIOD
+// generations, to tell what kind of object they are
+func Classify(cells []Point, rule Rule) Object {
+	o := classify(cells, rule)
+	if rule == CONWAY {
+		o.Name = ObjectName(o.Code)
+	}
+	return o
+}
+
+func classify(cells []Point, rule Rule) Object {
+	o := Object{Code: "zz_UNKNOWN", Cells: cells}
+	u, err := NewSparse(rule)
+	if err != nil || rule.States > 2 || len(cells) == 0 {
+		return o
+	}
+	for _, c := range cells {
+		u.SetCell(c.Row, c.Col, true)
+	}
+	first := u.Pattern()
+	start := u.Stats()
+	phases := []*Pattern{first}
+	for period := 1; period <= MAX_PERIOD; period++ {
+		u.Step()
+		p, st := u.Pattern(), u.Stats()
+		if p.Height != first.Height || p.Width != first.Width || !slices.Equal(p.Cells, first.Cells) {
+			phases = append(phases, p)
+			continue
+		}
+		o.Period = period
+		switch {
+		case st.X != start.X || st.Y != start.Y:
+			o.Kind = Spaceship
+			o.Code = fmt.Sprintf("xq%d_%s", period, canonical(phases))
+		case period == 1:
+			o.Kind = StillLife
+			o.Code = fmt.Sprintf("xs%d_%s", len(cells), canonical(phases))
+		default:
+			o.Kind = Oscillator
+			o.Code = fmt.Sprintf("xp%d_%s", period, canonical(phases))
+		}
+		return o
+	}
+	return o
+}
+
+// Stabilize steps u until its population, births and deaths repeat with a
+// period of at most MAX_PERIOD, for at most limit generations, and returns
+// the shortest such period. Spaceships flying off count as repeating.
+func Stabilize(u Universe, limit int) (period int, ok bool) {
+	type counts struct{ population, births, deaths int }
+	var history []counts
+	for gen := 0; gen <= limit; gen++ {
+		if gen > 0 {
+			u.Step()
+		}
+		st := u.Stats()
+		history = append(history, counts{st.Population, st.Births, st.Deaths})
+		if st.Population == 0 {
+			return 1, true
+		}
+		n := len(history)
+	periods:
+		for p := 1; p <= MAX_PERIOD; p++ {
+			// A few periods of repeats, and a couple dozen generations at least
+			window := max(4*p, 24)
+			if n < window+p {
+				break
+			}
+			for i := n - window; i < n; i++ {
+				if history[i] != history[i-p] {
+					continue periods
+				}
+			}
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// Separate splits the live cells of u into clusters of cells that touch each
+// other, or the same cells over the next generations, so every phase of an
+// oscillator and the path of a spaceship stay together. It steps u through
+// them, the clusters hold the cells alive before.
+func Separate(u Universe, generations int) [][]Point {
+	p, st := u.Pattern(), u.Stats()
+	alive := make(map[Point]bool, len(p.Cells))
+	for _, c := range p.Cells {
+		alive[Point{c.Row + st.Y, c.Col + st.X}] = true
+	}
+	visited := make(map[Point]bool)
+	add := func() {
+		p, st := u.Pattern(), u.Stats()
+		for _, c := range p.Cells {
+			visited[Point{c.Row + st.Y, c.Col + st.X}] = false
+		}
+	}
+	add()
+	for range generations {
+		u.Step()
+		add()
+	}
+	var clusters [][]Point
+	for start := range visited {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		var cluster []Point
+		queue := []Point{start}
+		for len(queue) > 0 {
+			c := queue[0]
+			queue = queue[1:]
+			if alive[c] {
+				cluster = append(cluster, c)
+			}
+			for dr := -1; dr <= 1; dr++ {
+				for dc := -1; dc <= 1; dc++ {
+					n := Point{c.Row + dr, c.Col + dc}
+					if seen, ok := visited[n]; ok && !seen {
+						visited[n] = true
+						queue = append(queue, n)
+					}
+				}
+			}
+		}
+		if len(cluster) > 0 {
+			slices.SortFunc(cluster, comparePoints)
+			clusters = append(clusters, cluster)
+		}
+	}
+	slices.SortFunc(clusters, func(a, b []Point) int {
+		return comparePoints(a[0], b[0])
+	})
+	return clusters
+}
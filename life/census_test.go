@@ -0,0 +1,181 @@
+package life
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// cellsOf returns the live cells of an RLE pattern moved by row, col
+func cellsOf(t *testing.T, rle string, row, col int) []Point {
+	t.Helper()
+	p, err := ParseRLE(strings.NewReader(rle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells := make([]Point, len(p.Cells))
+	for i, c := range p.Cells {
+		cells[i] = Point{c.Row + row, c.Col + col}
+	}
+	return cells
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		rle    string
+		code   string
+		kind   Kind
+		period int
+		// Well known name, "" if it has none
+		title string
+	}{
+		{"block", "x = 2, y = 2\n2o$2o!", "xs4_33", StillLife, 1, "Block"},
+		{"beehive", "x = 4, y = 3\nb2o$o2bo$b2o!", "xs6_696", StillLife, 1, "Beehive"},
+		{"beehive on end", "x = 3, y = 4\nbo$obo$obo$bo!", "xs6_696", StillLife, 1, "Beehive"},
+		{"boat", "x = 3, y = 3\n2o$obo$bo!", "xs5_253", StillLife, 1, "Boat"},
+		{"boat turned", "x = 3, y = 3\nbo$obo$b2o!", "xs5_253", StillLife, 1, "Boat"},
+		{"loaf mirrored", "x = 4, y = 4\nb2o$o2bo$obo$bo!", "xs7_2596", StillLife, 1, "Loaf"},
+		{"pond", "x = 4, y = 4\nb2o$o2bo$o2bo$b2o!", "xs8_6996", StillLife, 1, "Pond"},
+		{"blinker", "x = 3, y = 1\n3o!", "xp2_7", Oscillator, 2, "Blinker"},
+		{"blinker upright", "x = 1, y = 3\no$o$o!", "xp2_7", Oscillator, 2, "Blinker"},
+		{"toad", "x = 4, y = 2\nb3o$3o!", "xp2_7e", Oscillator, 2, "Toad"},
+		{"toad upright", "x = 2, y = 4\nbo$2o$2o$o!", "xp2_7e", Oscillator, 2, "Toad"},
+		{"toad other phase", "x = 4, y = 4\n2bo$o2bo$o2bo$bo!", "xp2_7e", Oscillator, 2, "Toad"},
+		{"beacon", "x = 4, y = 4\n2o$2o$2b2o$2b2o!", "xp2_318c", Oscillator, 2, "Beacon"},
+		{"beacon mirrored", "x = 4, y = 4\n2b2o$2b2o$2o$2o!", "xp2_318c", Oscillator, 2, "Beacon"},
+		{"glider", "x = 3, y = 3\nbo$2bo$3o!", "xq4_153", Spaceship, 4, "Glider"},
+		{"glider heading north west", "x = 3, y = 3\n3o$o$bo!", "xq4_153", Spaceship, 4, "Glider"},
+		{"glider other phase", "x = 3, y = 3\nobo$b2o$bo!", "xq4_153", Spaceship, 4, "Glider"},
+		{"LWSS", "x = 5, y = 4\nbo2bo$o4b$o3bo$4o!", "xq4_6frc", Spaceship, 4, "LWSS"},
+		{"LWSS heading east", "x = 5, y = 4\no2bo$4bo$o3bo$b4o!", "xq4_6frc", Spaceship, 4, "LWSS"},
+		{"LWSS heading south", "x = 4, y = 5\n3o$o2bo$o$o$bobo!", "xq4_6frc", Spaceship, 4, "LWSS"},
+		{"pentadecathlon", "x = 10, y = 3\n2bo4bo2b$2ob4ob2o$2bo4bo!", "xp15_4r4z4r4", Oscillator, 15, "Pentadecathlon"},
+		// Stood up, its code is shorter
+		{"bi-block has no name", "x = 5, y = 2\n2ob2o$2ob2o!", "xs8_rr", StillLife, 1, ""},
+		{"R-pentomino doesn't settle alone", "x = 3, y = 3\nb2o$2o$bo!", "zz_UNKNOWN", Unknown, 0, ""},
+	}
+	for _, tt := range tests {
+		for _, offset := range []Point{{0, 0}, {-37, 1000}} {
+			cells := cellsOf(t, tt.rle, offset.Row, offset.Col)
+			o := Classify(cells, CONWAY)
+			if o.Code != tt.code || o.Kind != tt.kind || o.Period != tt.period || o.Name != tt.title {
+				t.Errorf("%s at %v: %s, %s of period %d named %q, want %s, %s of period %d named %q", tt.name, offset, o.Code, o.Kind, o.Period, o.Name, tt.code, tt.kind, tt.period, tt.title)
+			}
+			if !slices.Equal(o.Cells, cells) {
+				t.Errorf("%s at %v: cells %v, want %v", tt.name, offset, o.Cells, cells)
+			}
+		}
+	}
+}
+
+func TestClassifyOtherRules(t *testing.T) {
+	// Names are only given under Life
+	o := Classify(cellsOf(t, "x = 2, y = 2\n2o$2o!", 0, 0), MustParseRule("B36/S23"))
+	if o.Code != "xs4_33" || o.Name != "" {
+		t.Errorf("block under HighLife: %s named %q, want xs4_33 named \"\"", o.Code, o.Name)
+	}
+	o = Classify(cellsOf(t, "x = 2, y = 2\n2o$2o!", 0, 0), MustParseRule("/2/3"))
+	if o.Code != "zz_UNKNOWN" {
+		t.Errorf("block under a Generations rule: %s, want zz_UNKNOWN", o.Code)
+	}
+}
+
+func TestWechsler(t *testing.T) {
+	tests := []struct {
+		rle  string
+		want string
+	}{
+		{"x = 3, y = 3\nbo$2bo$3o!", "456"},
+		{"x = 3, y = 3\n3o$2bo$bo!", "153"},
+		{"x = 1, y = 1\no!", "1"},
+		// Runs of empty columns
+		{"x = 4, y = 1\no2bo!", "1w1"},
+		{"x = 5, y = 1\no3bo!", "1x1"},
+		{"x = 6, y = 1\no4bo!", "1y01"},
+		{"x = 41, y = 1\no39bo!", "1yz1"},
+		{"x = 42, y = 1\no40bo!", "1yz01"},
+		{"x = 43, y = 1\no41bo!", "1yzw1"},
+		// A second strip of 5 rows, and an empty one
+		{"x = 1, y = 5\no4$o!", "h"},
+		{"x = 1, y = 6\no5$o!", "1z1"},
+		{"x = 1, y = 11\no10$o!", "1zz1"},
+	}
+	for _, tt := range tests {
+		p, err := ParseRLE(strings.NewReader(tt.rle))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Wechsler(p); got != tt.want {
+			t.Errorf("Wechsler of %q = %s, want %s", tt.rle, got, tt.want)
+		}
+	}
+}
+
+func TestStabilize(t *testing.T) {
+	tests := []struct {
+		name   string
+		rle    string
+		limit  int
+		period int
+		ok     bool
+	}{
+		{"block", "x = 2, y = 2\n2o$2o!", 100, 1, true},
+		// The counts of a blinker's phases are the same
+		{"blinker", "x = 3, y = 1\n3o!", 100, 1, true},
+		{"pentadecathlon", "x = 10, y = 3\n2bo4bo2b$2ob4ob2o$2bo4bo!", 200, 15, true},
+		{"pulsar", "x = 13, y = 13\n2b3o3b3o2$o4bobo4bo$o4bobo4bo$o4bobo4bo$2b3o3b3o2$2b3o3b3o$o4bobo4bo$o4bobo4bo$o4bobo4bo2$2b3o3b3o!", 200, 3, true},
+		{"glider", "x = 3, y = 3\nbo$2bo$3o!", 100, 1, true},
+		{"diehard dies", "x = 8, y = 3\n6bob$2o6b$bo3b3o!", 200, 1, true},
+		{"R-pentomino", "x = 3, y = 3\nb2o$2o$bo!", 2000, 1, true},
+		{"R-pentomino, too few generations", "x = 3, y = 3\nb2o$2o$bo!", 500, 0, false},
+	}
+	for _, tt := range tests {
+		u, err := NewSparse(CONWAY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range cellsOf(t, tt.rle, 0, 0) {
+			u.SetCell(c.Row, c.Col, true)
+		}
+		period, ok := Stabilize(u, tt.limit)
+		if period != tt.period || ok != tt.ok {
+			t.Errorf("%s: period %d, %v, want %d, %v", tt.name, period, ok, tt.period, tt.ok)
+		}
+	}
+}
+
+func TestSeparate(t *testing.T) {
+	// A block, a blinker, a glider and a toad, every phase of each one in a
+	// cluster apart from the others
+	u, err := NewSparse(CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := map[string]Point{
+		"x = 2, y = 2\n2o$2o!":     {0, 0},
+		"x = 3, y = 1\n3o!":        {0, 10},
+		"x = 3, y = 3\nbo$2bo$3o!": {10, 0},
+		"x = 4, y = 2\nb3o$3o!":    {10, 10},
+	}
+	var want [][]Point
+	for rle, at := range objects {
+		cells := cellsOf(t, rle, at.Row, at.Col)
+		for _, c := range cells {
+			u.SetCell(c.Row, c.Col, true)
+		}
+		want = append(want, cells)
+	}
+	slices.SortFunc(want, func(a, b []Point) int {
+		return comparePoints(a[0], b[0])
+	})
+	got := Separate(u, 4)
+	if len(got) != len(want) {
+		t.Fatalf("%d clusters, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("cluster %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+}
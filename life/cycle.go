@@ -0,0 +1,98 @@
+package life
+
+import "fmt"
+
+// Generations a Detector remembers, longer periods go unnoticed
+const DEFAULT_CYCLE_WINDOW = 1 << 16
+
+// Cycle is where a universe stopped changing
+type Cycle struct {
+	// First generation of the repeating part
+	Generation int
+	// Generations between repeats, 1 for still lifes, 0 once everything died
+	Period int
+}
+
+func (c Cycle) String() string {
+	if c.Period == 0 {
+		return fmt.Sprintf("died out at gen %d", c.Generation)
+	}
+	return fmt.Sprintf("stabilized: period %d at gen %d", c.Period, c.Generation)
+}
+
+// fingerprint is what two generations must share to count as a repeat,
+// more than the hash so a collision alone isn't enough
+type fingerprint struct {
+	hash  uint64
+	stats Stats
+}
+
+// Detector finds the first generation that repeats an earlier one, fed one
+// generation at a time
+type Detector struct {
+	// Window bounds how many generations are remembered
+	Window int
+	seen   map[fingerprint]int
+	// Generations observed, oldest first
+	order []fingerprint
+	last  int
+	// Fingerprint of the last generation observed
+	lastFP fingerprint
+	cycle  *Cycle
+}
+
+func NewDetector() *Detector {
+	d := &Detector{Window: DEFAULT_CYCLE_WINDOW}
+	d.Reset()
+	return d
+}
+
+// Reset forgets every generation observed
+func (d *Detector) Reset() {
+	d.seen = make(map[fingerprint]int)
+	d.order = nil
+	d.last = -1
+	d.cycle = nil
+}
+
+// Observe records the generation on u and returns the cycle it's part of,
+// if any. The last generation observed again is ignored, anything else but
+// the generation after it (a rewind, a skip, an edit of the last one)
+// starts over.
+func (d *Detector) Observe(u Universe) (Cycle, bool) {
+	st := u.Stats()
+	gen := st.Generation
+	// Births and deaths differ at the start of a cycle, the generation
+	// before it isn't part of it
+	st.Generation, st.Births, st.Deaths = 0, 0, 0
+	fp := fingerprint{u.Hash(), st}
+	switch {
+	case gen == d.last && fp == d.lastFP:
+		if d.cycle != nil {
+			return *d.cycle, true
+		}
+		return Cycle{}, false
+	case gen != d.last+1:
+		d.Reset()
+	}
+	d.last, d.lastFP = gen, fp
+	if d.cycle != nil {
+		return *d.cycle, true
+	}
+	if st.Width == 0 {
+		// Not a live or dying cell left
+		d.cycle = &Cycle{Generation: gen}
+		return *d.cycle, true
+	}
+	if gen, ok := d.seen[fp]; ok {
+		d.cycle = &Cycle{Generation: gen, Period: d.last - gen}
+		return *d.cycle, true
+	}
+	d.seen[fp] = d.last
+	d.order = append(d.order, fp)
+	if len(d.order) > d.Window {
+		delete(d.seen, d.order[0])
+		d.order = d.order[1:]
+	}
+	return Cycle{}, false
+}
@@ -0,0 +1,73 @@
+package life
+
+import "testing"
+
+func TestDetector(t *testing.T) {
+	u, err := NewSparse(CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.PlacePattern(catalogPattern(t, "Pulsar"), 0, 0)
+	d := NewDetector()
+	observe := func() (Cycle, bool) {
+		t.Helper()
+		c, ok := d.Observe(u)
+		// Seeing the same generation again changes nothing
+		if again, againOK := d.Observe(u); again != c || againOK != ok {
+			t.Fatalf("generation %d observed twice: %v, %v then %v, %v", u.Generation(), c, ok, again, againOK)
+		}
+		return c, ok
+	}
+	for range 3 {
+		if c, ok := observe(); ok {
+			t.Fatalf("generation %d: %v too soon", u.Generation(), c)
+		}
+		u.Step()
+	}
+	if c, ok := observe(); !ok || c != (Cycle{Generation: 0, Period: 3}) {
+		t.Fatalf("generation 3: %v, %v, want period 3 at gen 0", c, ok)
+	}
+
+	// Going back starts over
+	u.SetGeneration(1)
+	if c, ok := observe(); ok {
+		t.Fatalf("after going back: %v", c)
+	}
+	// So does an edit of the generation last seen
+	u.SetCell(40, 40, true)
+	u.SetCell(40, 41, true)
+	u.SetCell(41, 40, true)
+	u.SetCell(41, 41, true)
+	if c, ok := d.Observe(u); ok {
+		t.Fatalf("after an edit: %v", c)
+	}
+	for range 3 {
+		u.Step()
+		observe()
+	}
+	if c, ok := observe(); !ok || c != (Cycle{Generation: 1, Period: 3}) {
+		t.Fatalf("after an edit: %v, %v, want period 3 at gen 1", c, ok)
+	}
+
+	// A dead map is a cycle of its own
+	u.Clear()
+	if c, ok := observe(); !ok || c != (Cycle{}) {
+		t.Fatalf("dead map: %v, %v, want died out at gen 0", c, ok)
+	}
+}
+
+func TestDetectorDyingCells(t *testing.T) {
+	// Under Brian's Brain a lone cell dies, then takes a generation to decay
+	g := NewGrid(16, 16, MustParseRule("B2/S/C3"))
+	g.SetCell(5, 5, true)
+	d := NewDetector()
+	for range 2 {
+		if c, ok := d.Observe(g); ok {
+			t.Fatalf("generation %d with %d live cells: %v too soon", g.Generation(), g.Stats().Population, c)
+		}
+		g.Step()
+	}
+	if c, ok := d.Observe(g); !ok || c != (Cycle{Generation: 2}) {
+		t.Fatalf("generation 2: %v, %v, want died out at gen 2", c, ok)
+	}
+}
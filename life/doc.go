@@ -0,0 +1,5 @@
+// Package life implements Life-like cellular automata: a Grid that steps
+// any B/S rule, a bit-packed BitGrid for huge maps, fill presets, telling
+// objects apart by apgcode, and reading and writing of RLE and Plaintext
+// pattern files. It has no dependency on the terminal UI.
+package life
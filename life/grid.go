@@ -0,0 +1,557 @@
+package life
+
+import (
+	"sync"
+)
+
+// Stats describes one generation of the map, X/Y/Width/Height is the
+// bounding box of the live and dying cells. Births and Deaths count the cells that
+// changed in the last step, they stay 0 when it jumped several generations.
+type Stats struct {
+	Generation int `json:"generation"`
+	Population int `json:"population"`
+	X          int `json:"x"`
+	Y          int `json:"y"`
+	Width      int `json:"width"`
+	Height     int `json:"height"`
+	Births     int `json:"births"`
+	Deaths     int `json:"deaths"`
+}
+
+// Side of the square tiles a Grid tracks changes in
+const TILE_SIZE = 16
+
+// Grid is a fixed size Life map whose edges either wrap around (a torus) or
+// are surrounded by dead cells. It is safe for concurrent use.
+//
+// A cell whose neighborhood didn't change keeps its state, so a step only
+// visits the tiles that changed in the last one and the tiles around them.
+// Mostly empty maps step in time proportional to their activity, dense ones
+// fall back to visiting every cell. The population and the hash are kept up
+// to date as cells change, and the bounding box is found from the tiles that
+// hold any cells, so reading them doesn't scan the map either.
+type Grid struct {
+	mu sync.Mutex
+	// Cell states, see Rule
+	cells [][]uint8
+	// Buffer the next generation is stepped into. It matches cells
+	// everywhere but in the changed tiles.
+	next [][]uint8
+	// Tiles, row major, that changed in the last step or were edited since
+	changed []bool
+	// Tiles the next step visits
+	active []bool
+	// Live and dying cells in each tile
+	occupied []int
+	tileRows int
+	tileCols int
+	// hashRow^row and hashCol^col for every row and column
+	rowPow     []uint64
+	colPow     []uint64
+	population int
+	hash       uint64
+	rule       Rule
+	// Cells past the edges are dead instead of wrapping around
+	bounded bool
+	// Generations stepped since the map was created or cleared
+	generation int
+	// Cells born and died in the last step
+	births int
+	deaths int
+	height int
+	width  int
+}
+
+// NewGrid returns an empty height x width map that evolves under rule
+func NewGrid(height, width int, rule Rule) *Grid {
+	g := Grid{
+		cells:  make([][]uint8, height),
+		next:   make([][]uint8, height),
+		rule:   rule,
+		height: height,
+		width:  width,
+	}
+	for i := 0; i < g.height; i++ {
+		g.cells[i] = make([]uint8, g.width)
+		g.next[i] = make([]uint8, g.width)
+	}
+	g.tile()
+	return &g
+}
+
+// tile splits the map into tiles, all marked changed
+func (g *Grid) tile() {
+	g.tileRows = (g.height + TILE_SIZE - 1) / TILE_SIZE
+	g.tileCols = (g.width + TILE_SIZE - 1) / TILE_SIZE
+	g.changed = make([]bool, g.tileRows*g.tileCols)
+	g.active = make([]bool, g.tileRows*g.tileCols)
+	g.rowPow = powers(g.rowPow, hashRow, g.height)
+	g.colPow = powers(g.colPow, hashCol, g.width)
+	g.recount()
+	g.touchAll()
+}
+
+// powers extends the powers of x in p to n of them
+func powers(p []uint64, x uint64, n int) []uint64 {
+	for len(p) < n {
+		next := uint64(1)
+		if len(p) > 0 {
+			next = p[len(p)-1] * x
+		}
+		p = append(p, next)
+	}
+	return p
+}
+
+// recount counts the cells of every tile, the population and the hash over
+// the whole map
+func (g *Grid) recount() {
+	g.occupied = make([]int, g.tileRows*g.tileCols)
+	g.population, g.hash = 0, 0
+	for i := range g.height {
+		for j, state := range g.cells[i] {
+			if state != 0 {
+				g.occupied[i/TILE_SIZE*g.tileCols+j/TILE_SIZE]++
+				g.hash += g.rowPow[i] * g.colPow[j] * uint64(state)
+			}
+			if state == 1 {
+				g.population++
+			}
+		}
+	}
+}
+
+// count accounts for the cell at row r, column c of tile t going from one
+// state to another
+func (g *Grid) count(t, r, c int, from, to uint8) {
+	if from == 0 {
+		g.occupied[t]++
+	} else if to == 0 {
+		g.occupied[t]--
+	}
+	if from == 1 {
+		g.population--
+	} else if to == 1 {
+		g.population++
+	}
+	// Dying cells weigh their state so they hash apart
+	w := g.rowPow[r] * g.colPow[c]
+	g.hash += w*uint64(to) - w*uint64(from)
+}
+
+// set changes the cell at row r, column c to state
+func (g *Grid) set(r, c int, state uint8) {
+	t := r/TILE_SIZE*g.tileCols + c/TILE_SIZE
+	if old := g.cells[r][c]; old != state {
+		g.count(t, r, c, old, state)
+		g.cells[r][c] = state
+	}
+	g.changed[t] = true
+}
+
+// touchAll marks every tile changed, the next step visits every cell
+func (g *Grid) touchAll() {
+	for i := range g.changed {
+		g.changed[i] = true
+	}
+}
+
+// activate marks the tiles the next step visits, the changed ones and their
+// neighbors, and clears the changed ones
+func (g *Grid) activate() {
+	changed := 0
+	for _, ch := range g.changed {
+		if ch {
+			changed++
+		}
+	}
+	// Too busy for tracking tiles to pay off
+	dense := changed*2 > len(g.changed)
+	for i := range g.active {
+		g.active[i] = dense
+	}
+	for t, ch := range g.changed {
+		g.changed[t] = false
+		if !ch || dense {
+			continue
+		}
+		tr, tc := t/g.tileCols, t%g.tileCols
+		for i := tr - 1; i <= tr+1; i++ {
+			for j := tc - 1; j <= tc+1; j++ {
+				if g.bounded && (i < 0 || i >= g.tileRows || j < 0 || j >= g.tileCols) {
+					continue
+				}
+				i, j := (i+g.tileRows)%g.tileRows, (j+g.tileCols)%g.tileCols
+				g.active[i*g.tileCols+j] = true
+			}
+		}
+	}
+}
+
+// NewBoundedGrid returns an empty height x width map whose edges don't wrap,
+// everything past them stays dead
+func NewBoundedGrid(height, width int, rule Rule) *Grid {
+	g := NewGrid(height, width, rule)
+	g.bounded = true
+	return g
+}
+
+func (g *Grid) Topology() Topology {
+	if g.bounded {
+		return Bounded
+	}
+	return Torus
+}
+
+// boundedNeighbors counts neighbors treating cells past the edges as dead
+func (g *Grid) boundedNeighbors(gameMap [][]uint8, r int, c int) int {
+	total := 0
+	for i := max(r-1, 0); i <= min(r+1, g.height-1); i++ {
+		for j := max(c-1, 0); j <= min(c+1, g.width-1); j++ {
+			if gameMap[i][j] == 1 && (i != r || j != c) {
+				total += 1
+			}
+		}
+	}
+	return total
+}
+
+func (g *Grid) neighbors(gameMap [][]uint8, r int, c int) int {
+	total := 0
+	var adr, bdr, dc int
+	if r > 0 {
+		adr = r - 1
+	} else {
+		adr = g.height - 1
+	}
+	bdr = (r + 1) % g.height
+	if c > 0 {
+		dc = c - 1
+	} else {
+		dc = g.width - 1
+	}
+	if gameMap[r][dc] == 1 {
+		total += 1
+	}
+	for range 3 {
+		if gameMap[adr][dc] == 1 {
+			total += 1
+		}
+		if gameMap[bdr][dc] == 1 {
+			total += 1
+		}
+		dc = (dc + 1) % g.width
+	}
+	if gameMap[r][(c+1)%g.width] == 1 {
+		total += 1
+	}
+	return total
+}
+
+// Step advances the map by one generation
+func (g *Grid) Step() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.births, g.deaths = 0, 0
+	// Last dying state, and the state live cells die into
+	last := uint8(g.rule.NumStates() - 1)
+	died := uint8(0)
+	if last > 1 {
+		died = 2
+	}
+	g.activate()
+	for t, active := range g.active {
+		if !active {
+			continue
+		}
+		top, left := t/g.tileCols*TILE_SIZE, t%g.tileCols*TILE_SIZE
+		for r := top; r < min(top+TILE_SIZE, g.height); r++ {
+			for c := left; c < min(left+TILE_SIZE, g.width); c++ {
+				state := g.cells[r][c]
+				next := state
+				//Dying cell
+				if state > 1 {
+					if state < last {
+						next++
+					} else {
+						next = 0
+					}
+				} else {
+					var n int
+					if g.bounded {
+						n = g.boundedNeighbors(g.cells, r, c)
+					} else {
+						n = g.neighbors(g.cells, r, c)
+					}
+					//Live cell
+					if state == 1 && !g.rule.Survive[n] {
+						next = died
+						g.deaths++
+						//Dead cell
+					} else if state == 0 && g.rule.Birth[n] {
+						next = 1
+						g.births++
+					}
+				}
+				g.next[r][c] = next
+				if next != state {
+					g.changed[t] = true
+					g.count(t, r, c, state, next)
+				}
+			}
+		}
+	}
+	g.cells, g.next = g.next, g.cells
+	g.generation++
+}
+
+// StepN advances the map by n generations
+func (g *Grid) StepN(n int) {
+	for range n {
+		g.Step()
+	}
+}
+
+// PlacePattern sets the live and dying cells of p with its top left corner
+// at (x, y), cells that fall off the board are dropped
+func (g *Grid) PlacePattern(p *Pattern, x, y int) {
+	for _, c := range p.Cells {
+		g.SetCell(x+c.Row, y+c.Col, true)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	last := g.rule.NumStates() - 1
+	for _, c := range p.Dying {
+		row, col := x+c.Row, y+c.Col
+		if row >= 0 && row < g.height && col >= 0 && col < g.width && int(c.State) <= last {
+			g.set(row, col, c.State)
+		}
+	}
+}
+
+// Pattern returns the live and dying cells of the map cropped to their
+// bounding box, tagged with the active rule
+func (g *Grid) Pattern() *Pattern {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	top, left, height, width := g.bounds()
+	rule := g.rule
+	p := NewPattern(height, width)
+	p.Rule = &rule
+	p.Cells = make([]Point, 0, g.population)
+	for i := range height {
+		row := g.cells[top+i]
+		// Row by row for the order of Cells, skipping empty tiles
+		for tc := left / TILE_SIZE; tc*TILE_SIZE < left+width; tc++ {
+			if g.occupied[(top+i)/TILE_SIZE*g.tileCols+tc] == 0 {
+				continue
+			}
+			for c := max(tc*TILE_SIZE, left); c < min((tc+1)*TILE_SIZE, left+width); c++ {
+				switch state := row[c]; state {
+				case 0:
+				case 1:
+					p.Cells = append(p.Cells, Point{i, c - left})
+				default:
+					p.Dying = append(p.Dying, DyingCell{Point{i, c - left}, state})
+				}
+			}
+		}
+	}
+	return p
+}
+
+// bounds returns the bounding box of the live and dying cells, the box is
+// empty when there are no such cells. Only the tiles on the edges of the
+// box of occupied tiles are scanned.
+func (g *Grid) bounds() (top, left, height, width int) {
+	minRow, minCol, maxRow, maxCol := g.tileRows, g.tileCols, -1, -1
+	for t, n := range g.occupied {
+		if n > 0 {
+			tr, tc := t/g.tileCols, t%g.tileCols
+			minRow, maxRow = min(minRow, tr), max(maxRow, tr)
+			minCol, maxCol = min(minCol, tc), max(maxCol, tc)
+		}
+	}
+	if maxRow < 0 {
+		return 0, 0, 0, 0
+	}
+	top, left, bottom, right := g.height, g.width, -1, -1
+	for tr := minRow; tr <= maxRow; tr++ {
+		for tc := minCol; tc <= maxCol; tc++ {
+			edge := tr == minRow || tr == maxRow || tc == minCol || tc == maxCol
+			if !edge || g.occupied[tr*g.tileCols+tc] == 0 {
+				continue
+			}
+			for i := tr * TILE_SIZE; i < min((tr+1)*TILE_SIZE, g.height); i++ {
+				for j := tc * TILE_SIZE; j < min((tc+1)*TILE_SIZE, g.width); j++ {
+					if g.cells[i][j] != 0 {
+						top, bottom = min(top, i), max(bottom, i)
+						left, right = min(left, j), max(right, j)
+					}
+				}
+			}
+		}
+	}
+	return top, left, bottom - top + 1, right - left + 1
+}
+
+// Stats summarizes the current generation
+func (g *Grid) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	top, left, height, width := g.bounds()
+	return Stats{
+		Generation: g.generation,
+		Population: g.population,
+		X:          left,
+		Y:          top,
+		Width:      width,
+		Height:     height,
+		Births:     g.births,
+		Deaths:     g.deaths,
+	}
+}
+
+func (g *Grid) Hash() uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.hash
+}
+
+// Clear kills every cell and resets the generation count
+func (g *Grid) Clear() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.generation = 0
+	g.births, g.deaths = 0, 0
+	for i := 0; i < g.height; i++ {
+		for j := 0; j < g.width; j++ {
+			g.cells[i][j] = 0
+		}
+	}
+	g.recount()
+	g.touchAll()
+}
+
+// Resize grows the map to at least height x width, it never shrinks
+func (g *Grid) Resize(height, width int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	wDiff := width - g.width
+	hDiff := height - g.height
+	if wDiff > 0 {
+		for i := 0; i < g.height; i++ {
+			for range wDiff {
+				g.cells[i] = append(g.cells[i], 0)
+				g.next[i] = append(g.next[i], 0)
+			}
+		}
+		g.width = width
+	}
+	if hDiff > 0 {
+		for range hDiff {
+			g.cells = append(g.cells, make([]uint8, g.width))
+			g.next = append(g.next, make([]uint8, g.width))
+		}
+		g.height = height
+	}
+	if wDiff > 0 || hDiff > 0 {
+		g.tile()
+	}
+}
+
+// SetCell sets the cell at row x, column y, cells off the map are ignored
+func (g *Grid) SetCell(x, y int, b bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if x < 0 || x >= g.height {
+		return
+	}
+	if y < 0 || y >= g.width {
+		return
+	}
+	state := uint8(0)
+	if b {
+		state = 1
+	}
+	g.set(x, y, state)
+}
+
+// Cell reports whether the cell at row x, column y is alive, cells off the
+// map are dead
+func (g *Grid) Cell(x, y int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if x < 0 || x >= g.height {
+		return false
+	}
+	if y < 0 || y >= g.width {
+		return false
+	}
+	return g.cells[x][y] == 1
+}
+
+func (g *Grid) Region(top, left, height, width int) [][]bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cells := newRegion(height, width)
+	for i := max(top, 0); i < min(top+height, g.height); i++ {
+		for j := max(left, 0); j < min(left+width, g.width); j++ {
+			cells[i-top][j-left] = g.cells[i][j] == 1
+		}
+	}
+	return cells
+}
+
+func (g *Grid) RegionStates(top, left, height, width int) [][]uint8 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cells := newStates(height, width)
+	from, to := max(left, 0), min(left+width, g.width)
+	for i := max(top, 0); i < min(top+height, g.height) && from < to; i++ {
+		copy(cells[i-top][from-left:], g.cells[i][from:to])
+	}
+	return cells
+}
+
+func (g *Grid) Size() (height, width int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.height, g.width
+}
+
+func (g *Grid) Generation() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.generation
+}
+
+func (g *Grid) SetGeneration(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.generation = n
+}
+
+// SetRule switches to rule, dying cells past its last state die
+func (g *Grid) SetRule(rule Rule) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rule = rule
+	last := uint8(rule.NumStates() - 1)
+	for i := range g.cells {
+		for j, state := range g.cells[i] {
+			if state > last {
+				g.set(i, j, 0)
+			}
+		}
+	}
+	// Cells whose neighborhood didn't change may still change under rule
+	g.touchAll()
+	return nil
+}
+
+func (g *Grid) Rule() Rule {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rule
+}
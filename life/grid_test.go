@@ -0,0 +1,147 @@
+package life
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// gridEdit changes a map between runs of steps
+type gridEdit struct {
+	name string
+	edit func(g *Grid)
+}
+
+// sameGrid fails unless the tile tracked map g has the cells and stats of
+// full, whose every step visited every cell
+func sameGrid(t *testing.T, step string, g, full *Grid) {
+	t.Helper()
+	height, width := full.Size()
+	got, want := g.RegionStates(0, 0, height, width), full.RegionStates(0, 0, height, width)
+	for r := range want {
+		if c := slices.Compare(got[r], want[r]); c != 0 {
+			t.Fatalf("%s: row %d is %v, want %v", step, r, got[r], want[r])
+		}
+	}
+	if gs, fs := g.Stats(), full.Stats(); gs != fs {
+		t.Fatalf("%s: stats %+v, want %+v", step, gs, fs)
+	}
+	// The counts kept as cells change agree with counting every cell
+	st, hash := scan(want)
+	if gs := g.Stats(); gs.Population != st.Population || gs.X != st.X || gs.Y != st.Y || gs.Width != st.Width || gs.Height != st.Height {
+		t.Fatalf("%s: stats %+v, want %+v", step, gs, st)
+	}
+	if g.Hash() != hash {
+		t.Fatalf("%s: hash %#x, want %#x", step, g.Hash(), hash)
+	}
+}
+
+// scan counts the population, bounding box and hash of the cell states
+func scan(states [][]uint8) (Stats, uint64) {
+	var st Stats
+	var hash uint64
+	top, left, bottom, right := len(states), len(states[0]), -1, -1
+	for i, row := range states {
+		for j, state := range row {
+			if state != 0 {
+				top, bottom = min(top, i), max(bottom, i)
+				left, right = min(left, j), max(right, j)
+				hash += cellHash(i, j) * uint64(state)
+			}
+			if state == 1 {
+				st.Population++
+			}
+		}
+	}
+	if bottom >= 0 {
+		st.X, st.Y, st.Width, st.Height = left, top, right-left+1, bottom-top+1
+	}
+	return st, hash
+}
+
+func TestGridActiveTiles(t *testing.T) {
+	glider := catalogPattern(t, "Glider")
+	edits := func(height, width int) []gridEdit {
+		return []gridEdit{
+			{"glider across tile borders", func(g *Grid) {
+				g.PlacePattern(glider, TILE_SIZE-2, TILE_SIZE-2)
+			}},
+			{"blinker across a tile border", func(g *Grid) {
+				for c := TILE_SIZE - 1; c <= TILE_SIZE+1; c++ {
+					g.SetCell(2*TILE_SIZE-1, c, true)
+				}
+			}},
+			{"block on a tile corner", func(g *Grid) {
+				for _, c := range []Point{{TILE_SIZE - 1, 2*TILE_SIZE - 1}, {TILE_SIZE - 1, 2 * TILE_SIZE}, {TILE_SIZE, 2*TILE_SIZE - 1}, {TILE_SIZE, 2 * TILE_SIZE}} {
+					g.SetCell(c.Row, c.Col, true)
+				}
+			}},
+			{"killing a cell of the blinker", func(g *Grid) {
+				g.SetCell(2*TILE_SIZE-1, TILE_SIZE, false)
+				g.SetCell(2*TILE_SIZE, TILE_SIZE, false)
+			}},
+			{"blinker across the wrap edge", func(g *Grid) {
+				for _, c := range []int{width - 1, 0, 1} {
+					g.SetCell(0, c, true)
+				}
+			}},
+			{"glider flying over the corner", func(g *Grid) {
+				g.PlacePattern(glider, height-4, width-4)
+			}},
+			{"soup over the whole map", func(g *Grid) {
+				soup(g, height, width, 1)
+			}},
+			{"soup in the middle", func(g *Grid) {
+				g.Clear()
+				sub := NewGrid(20, 20, g.Rule())
+				soup(sub, 20, 20, 7)
+				g.PlacePattern(sub.Pattern(), height/2-10, width/2-10)
+			}},
+			{"resize", func(g *Grid) {
+				g.Resize(height+5, width+TILE_SIZE+3)
+			}},
+			{"glider on the new edge", func(g *Grid) {
+				h, w := g.Size()
+				g.PlacePattern(glider, h-3, w-3)
+			}},
+			{"HighLife", func(g *Grid) {
+				g.SetRule(MustParseRule("B36/S23"))
+			}},
+			{"Generations rule", func(g *Grid) {
+				g.SetRule(MustParseRule("B2/S345/C5"))
+			}},
+			{"dying cells placed on a tile border", func(g *Grid) {
+				p := &Pattern{Height: 1, Width: 3, Cells: []Point{{0, 0}}, Dying: []DyingCell{{Point{0, 1}, 2}, {Point{0, 2}, 4}}}
+				g.PlacePattern(p, TILE_SIZE, TILE_SIZE-1)
+			}},
+			{"back to Life", func(g *Grid) {
+				g.SetRule(CONWAY)
+			}},
+		}
+	}
+	topologies := []struct {
+		name string
+		new  func(height, width int) *Grid
+	}{
+		{"torus", func(height, width int) *Grid { return NewGrid(height, width, CONWAY) }},
+		{"bounded", func(height, width int) *Grid { return NewBoundedGrid(height, width, CONWAY) }},
+	}
+	for _, topology := range topologies {
+		// Whole tiles, and tiles cut short by the edges
+		for _, size := range [][2]int{{3 * TILE_SIZE, 4 * TILE_SIZE}, {50, 70}, {TILE_SIZE + 1, 5}} {
+			height, width := size[0], size[1]
+			g, full := topology.new(height, width), topology.new(height, width)
+			for _, e := range edits(height, width) {
+				e.edit(g)
+				e.edit(full)
+				for gen := range 60 {
+					step := fmt.Sprintf("%s %dx%d, %s, step %d", topology.name, width, height, e.name, gen+1)
+					full.touchAll()
+					full.Step()
+					g.Step()
+					sameGrid(t, step, g, full)
+				}
+			}
+		}
+	}
+}
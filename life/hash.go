@@ -0,0 +1,38 @@
+package life
+
+// A universe hashes to the sum of hashRow^row * hashCol^col over its live
+// cells (mod 2^64). Every engine agrees on it, and a Hashlife node hashed
+// once can be moved anywhere with two multiplications.
+const (
+	hashRow uint64 = 0x9E3779B97F4A7C15
+	hashCol uint64 = 0xC2B2AE3D27D4EB4F
+)
+
+// inverse returns the multiplicative inverse of an odd x mod 2^64
+func inverse(x uint64) uint64 {
+	// Newton's method, each round doubles the correct low bits
+	y := x
+	for range 5 {
+		y *= 2 - x*y
+	}
+	return y
+}
+
+// pow returns x^n mod 2^64, negative n needs an odd x
+func pow(x uint64, n int) uint64 {
+	if n < 0 {
+		x, n = inverse(x), -n
+	}
+	r := uint64(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			r *= x
+		}
+		x *= x
+	}
+	return r
+}
+
+func cellHash(row, col int) uint64 {
+	return pow(hashRow, row) * pow(hashCol, col)
+}
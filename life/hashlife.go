@@ -0,0 +1,587 @@
+package life
+
+import (
+	"sync"
+)
+
+// Default node budget of a Hashlife universe before its caches are dropped
+const DEFAULT_MAX_NODES = 1 << 21
+
+// node is a square of 2^level x 2^level cells. Nodes are canonical: two
+// nodes with the same children are the same pointer, so identical regions
+// anywhere in space or time share one node and its memoized future.
+type node struct {
+	nw, ne, sw, se *node
+	level          int
+	population     int
+	// Center of the node 2^(level-2) generations later
+	next *node
+	// Hash of the cells with the top left one at 0, 0, 0 until computed
+	hash uint64
+}
+
+type quad struct {
+	nw, ne, sw, se *node
+}
+
+type slowKey struct {
+	n *node
+	j int
+}
+
+// Hashlife is an unbounded Life universe stored as a memoized quadtree
+// (https://en.wikipedia.org/wiki/Hashlife). Stepping 2^k generations costs
+// about as much as stepping one, which makes it the engine of choice for
+// huge or long running patterns. It is safe for concurrent use.
+type Hashlife struct {
+	mu   sync.Mutex
+	rule Rule
+	root *node
+	// Coordinates of the root's top left cell
+	top        int
+	left       int
+	generation int
+	// Cells born and died in the last step, only counted for single steps
+	births int
+	deaths int
+	// MaxNodes bounds the node table, the memoized results are dropped when
+	// it grows past it
+	MaxNodes int
+
+	nodes  map[quad]*node
+	slow   map[slowKey]*node
+	empty  []*node
+	leaves [2]*node
+}
+
+// NewHashlife returns an empty unbounded universe that evolves under rule
+func NewHashlife(rule Rule) (*Hashlife, error) {
+	if rule.Birth[0] {
+		return nil, ErrBirthOnZero
+	}
+	if rule.States > 2 {
+		return nil, ErrGenerations
+	}
+	h := &Hashlife{
+		rule:     rule,
+		MaxNodes: DEFAULT_MAX_NODES,
+		leaves:   [2]*node{{level: 0}, {level: 0, population: 1}},
+	}
+	h.reset()
+	return h, nil
+}
+
+func (h *Hashlife) reset() {
+	h.nodes = make(map[quad]*node)
+	h.slow = make(map[slowKey]*node)
+	h.empty = nil
+	h.root = h.emptyNode(3)
+	h.top, h.left = -4, -4
+}
+
+func (h *Hashlife) join(nw, ne, sw, se *node) *node {
+	k := quad{nw, ne, sw, se}
+	if n, ok := h.nodes[k]; ok {
+		return n
+	}
+	n := &node{
+		nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+	}
+	h.nodes[k] = n
+	return n
+}
+
+func (h *Hashlife) emptyNode(level int) *node {
+	for len(h.empty) <= level {
+		if len(h.empty) == 0 {
+			h.empty = append(h.empty, h.leaves[0])
+			continue
+		}
+		e := h.empty[len(h.empty)-1]
+		h.empty = append(h.empty, h.join(e, e, e, e))
+	}
+	return h.empty[level]
+}
+
+// center returns the middle half of n
+func (h *Hashlife) center(n *node) *node {
+	return h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// expand wraps the root in an empty border, doubling its size
+func (h *Hashlife) expand() {
+	r := h.root
+	e := h.emptyNode(r.level - 1)
+	h.root = h.join(
+		h.join(e, e, e, r.nw),
+		h.join(e, e, r.ne, e),
+		h.join(e, r.sw, e, e),
+		h.join(r.se, e, e, e),
+	)
+	half := 1 << (r.level - 1)
+	h.top -= half
+	h.left -= half
+}
+
+// shrink drops empty borders from the root
+func (h *Hashlife) shrink() {
+	for h.root.level > 3 {
+		c := h.center(h.root)
+		if c.population != h.root.population {
+			return
+		}
+		quarter := 1 << (h.root.level - 2)
+		h.root = c
+		h.top += quarter
+		h.left += quarter
+	}
+}
+
+// evolve returns the center of n advanced by 2^j generations, j may not
+// exceed n.level-2
+func (h *Hashlife) evolve(n *node, j int) *node {
+	if n.population == 0 {
+		return h.emptyNode(n.level - 1)
+	}
+	full := j >= n.level-2
+	if full {
+		if n.next != nil {
+			return n.next
+		}
+	} else if r, ok := h.slow[slowKey{n, j}]; ok {
+		return r
+	}
+
+	var result *node
+	if n.level == 2 {
+		result = h.step4x4(n)
+	} else {
+		// Nine overlapping subnodes of half the size
+		n00 := n.nw
+		n01 := h.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw)
+		n02 := n.ne
+		n10 := h.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne)
+		n11 := h.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+		n12 := h.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne)
+		n20 := n.sw
+		n21 := h.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw)
+		n22 := n.se
+		var r [9]*node
+		for i, sub := range [9]*node{n00, n01, n02, n10, n11, n12, n20, n21, n22} {
+			if full {
+				// First half of the jump
+				r[i] = h.evolve(sub, j)
+			} else {
+				r[i] = h.center(sub)
+			}
+		}
+		result = h.join(
+			h.evolve(h.join(r[0], r[1], r[3], r[4]), j),
+			h.evolve(h.join(r[1], r[2], r[4], r[5]), j),
+			h.evolve(h.join(r[3], r[4], r[6], r[7]), j),
+			h.evolve(h.join(r[4], r[5], r[7], r[8]), j),
+		)
+	}
+	if full {
+		n.next = result
+	} else {
+		h.slow[slowKey{n, j}] = result
+	}
+	return result
+}
+
+// step4x4 advances the middle 2x2 cells of a level 2 node by one generation
+func (h *Hashlife) step4x4(n *node) *node {
+	var cells [4][4]bool
+	for i, q := range [4]*node{n.nw, n.ne, n.sw, n.se} {
+		for k, leaf := range [4]*node{q.nw, q.ne, q.sw, q.se} {
+			cells[(i/2)*2+k/2][(i%2)*2+k%2] = leaf.population == 1
+		}
+	}
+	next := func(r, c int) *node {
+		total := 0
+		for dr := -1; dr <= 1; dr++ {
+			for dc := -1; dc <= 1; dc++ {
+				if (dr != 0 || dc != 0) && cells[r+dr][c+dc] {
+					total++
+				}
+			}
+		}
+		if cells[r][c] && h.rule.Survive[total] || !cells[r][c] && h.rule.Birth[total] {
+			return h.leaves[1]
+		}
+		return h.leaves[0]
+	}
+	return h.join(next(1, 1), next(1, 2), next(2, 1), next(2, 2))
+}
+
+// advance steps the universe by 2^k generations
+func (h *Hashlife) advance(k int) {
+	if len(h.nodes) > h.MaxNodes {
+		h.gc()
+	}
+	// The pattern has to sit in the middle half of the root with room to
+	// grow by 2^k cells in every direction
+	for h.root.level < k+2 || h.center(h.root).population != h.root.population {
+		h.expand()
+	}
+	h.expand()
+	quarter := 1 << (h.root.level - 2)
+	prev := h.center(h.root)
+	h.root = h.evolve(h.root, k)
+	h.births, h.deaths = 0, 0
+	if k == 0 {
+		h.births, h.deaths = diff(h.root, prev), diff(prev, h.root)
+	}
+	h.top += quarter
+	h.left += quarter
+	h.generation += 1 << k
+	h.shrink()
+}
+
+// diff counts the cells alive in a but dead in b, two nodes covering the
+// same square. Shared subtrees are skipped, so it costs about as much as the
+// change between them.
+func diff(a, b *node) int {
+	switch {
+	case a == b || a.population == 0:
+		return 0
+	case b.population == 0 || a.level == 0:
+		return a.population
+	}
+	return diff(a.nw, b.nw) + diff(a.ne, b.ne) + diff(a.sw, b.sw) + diff(a.se, b.se)
+}
+
+// gc drops every memoized result and the nodes that aren't part of the
+// current pattern
+func (h *Hashlife) gc() {
+	h.nodes = make(map[quad]*node)
+	h.slow = make(map[slowKey]*node)
+	h.empty = nil
+	var rehash func(n *node)
+	rehash = func(n *node) {
+		if n.level == 0 {
+			return
+		}
+		k := quad{n.nw, n.ne, n.sw, n.se}
+		if _, ok := h.nodes[k]; ok {
+			return
+		}
+		n.next = nil
+		h.nodes[k] = n
+		rehash(n.nw)
+		rehash(n.ne)
+		rehash(n.sw)
+		rehash(n.se)
+	}
+	rehash(h.root)
+}
+
+func (h *Hashlife) Step() {
+	h.StepN(1)
+}
+
+// StepN advances the universe by n generations in O(log n) jumps of a
+// power of two generations each. The universe is unlocked between jumps, so
+// it can be read while a long one runs.
+func (h *Hashlife) StepN(n int) {
+	for k := 0; n > 0; k++ {
+		if n&1 == 1 {
+			h.mu.Lock()
+			h.advance(k)
+			h.mu.Unlock()
+		}
+		n >>= 1
+	}
+}
+
+func (h *Hashlife) contains(row, col int) bool {
+	size := 1 << h.root.level
+	return row >= h.top && row < h.top+size && col >= h.left && col < h.left+size
+}
+
+func (h *Hashlife) SetCell(row, col int, alive bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for !h.contains(row, col) {
+		if !alive {
+			return
+		}
+		h.expand()
+	}
+	h.root = h.set(h.root, row-h.top, col-h.left, alive)
+}
+
+func (h *Hashlife) set(n *node, row, col int, alive bool) *node {
+	if n.level == 0 {
+		if alive {
+			return h.leaves[1]
+		}
+		return h.leaves[0]
+	}
+	half := 1 << (n.level - 1)
+	nw, ne, sw, se := n.nw, n.ne, n.sw, n.se
+	switch {
+	case row < half && col < half:
+		nw = h.set(nw, row, col, alive)
+	case row < half:
+		ne = h.set(ne, row, col-half, alive)
+	case col < half:
+		sw = h.set(sw, row-half, col, alive)
+	default:
+		se = h.set(se, row-half, col-half, alive)
+	}
+	return h.join(nw, ne, sw, se)
+}
+
+func (h *Hashlife) Cell(row, col int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.contains(row, col) {
+		return false
+	}
+	n := h.root
+	row, col = row-h.top, col-h.left
+	for n.level > 0 {
+		if n.population == 0 {
+			return false
+		}
+		half := 1 << (n.level - 1)
+		switch {
+		case row < half && col < half:
+			n = n.nw
+		case row < half:
+			n, col = n.ne, col-half
+		case col < half:
+			n, row = n.sw, row-half
+		default:
+			n, row, col = n.se, row-half, col-half
+		}
+	}
+	return n.population == 1
+}
+
+func (h *Hashlife) Region(top, left, height, width int) [][]bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	cells := newRegion(height, width)
+	var fill func(n *node, row, col int)
+	fill = func(n *node, row, col int) {
+		size := 1 << n.level
+		if n.population == 0 || row >= top+height || row+size <= top || col >= left+width || col+size <= left {
+			return
+		}
+		if n.level == 0 {
+			cells[row-top][col-left] = true
+			return
+		}
+		half := size / 2
+		fill(n.nw, row, col)
+		fill(n.ne, row, col+half)
+		fill(n.sw, row+half, col)
+		fill(n.se, row+half, col+half)
+	}
+	fill(h.root, h.top, h.left)
+	return cells
+}
+
+// RegionStates is Region as states, Hashlife only runs two state rules
+func (h *Hashlife) RegionStates(top, left, height, width int) [][]uint8 {
+	cells := newStates(height, width)
+	for i, row := range h.Region(top, left, height, width) {
+		for j, alive := range row {
+			if alive {
+				cells[i][j] = 1
+			}
+		}
+	}
+	return cells
+}
+
+// forEachLive calls fn with the coordinates of every live cell in n
+func forEachLive(n *node, top, left int, fn func(row, col int)) {
+	if n.population == 0 {
+		return
+	}
+	if n.level == 0 {
+		fn(top, left)
+		return
+	}
+	half := 1 << (n.level - 1)
+	forEachLive(n.nw, top, left, fn)
+	forEachLive(n.ne, top, left+half, fn)
+	forEachLive(n.sw, top+half, left, fn)
+	forEachLive(n.se, top+half, left+half, fn)
+}
+
+// edge returns the offset of the first live row of n counting from the top,
+// or with flip from the bottom; memo spares revisiting shared subtrees
+func edge(n *node, flip bool, memo map[*node]int) int {
+	if n.level == 0 {
+		return 0
+	}
+	if e, ok := memo[n]; ok {
+		return e
+	}
+	half := 1 << (n.level - 1)
+	a, b, c, d := n.nw, n.ne, n.sw, n.se
+	if flip {
+		a, b, c, d = c, d, a, b
+	}
+	var e int
+	if a.population+b.population > 0 {
+		e = 1 << n.level
+		if a.population > 0 {
+			e = edge(a, flip, memo)
+		}
+		if b.population > 0 {
+			e = min(e, edge(b, flip, memo))
+		}
+	} else {
+		e = 1 << n.level
+		if c.population > 0 {
+			e = edge(c, flip, memo)
+		}
+		if d.population > 0 {
+			e = min(e, edge(d, flip, memo))
+		}
+		e += half
+	}
+	memo[n] = e
+	return e
+}
+
+// transpose returns n mirrored along its main diagonal, so edge can find
+// columns as well as rows
+func (h *Hashlife) transpose(n *node, memo map[*node]*node) *node {
+	if n.level == 0 || n.population == 0 {
+		return n
+	}
+	if t, ok := memo[n]; ok {
+		return t
+	}
+	t := h.join(h.transpose(n.nw, memo), h.transpose(n.sw, memo), h.transpose(n.ne, memo), h.transpose(n.se, memo))
+	memo[n] = t
+	return t
+}
+
+// bounds returns the bounding box of the live cells, the box is empty when
+// there are no live cells
+func (h *Hashlife) bounds() (top, left, height, width int) {
+	if h.root.population == 0 {
+		return 0, 0, 0, 0
+	}
+	size := 1 << h.root.level
+	top = edge(h.root, false, map[*node]int{})
+	bottom := size - 1 - edge(h.root, true, map[*node]int{})
+	t := h.transpose(h.root, map[*node]*node{})
+	left = edge(t, false, map[*node]int{})
+	right := size - 1 - edge(t, true, map[*node]int{})
+	return h.top + top, h.left + left, bottom - top + 1, right - left + 1
+}
+
+func (h *Hashlife) PlacePattern(p *Pattern, row, col int) {
+	for _, c := range p.Cells {
+		h.SetCell(row+c.Row, col+c.Col, true)
+	}
+}
+
+func (h *Hashlife) Pattern() *Pattern {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	top, left, height, width := h.bounds()
+	rule := h.rule
+	p := NewPattern(height, width)
+	p.Rule = &rule
+	p.Cells = make([]Point, 0, h.root.population)
+	forEachLive(h.root, h.top, h.left, func(row, col int) {
+		p.Cells = append(p.Cells, Point{row - top, col - left})
+	})
+	p.sortCells()
+	return p
+}
+
+func (h *Hashlife) Stats() Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	top, left, height, width := h.bounds()
+	return Stats{
+		Generation: h.generation,
+		Population: h.root.population,
+		X:          left,
+		Y:          top,
+		Width:      width,
+		Height:     height,
+		Births:     h.births,
+		Deaths:     h.deaths,
+	}
+}
+
+func (h *Hashlife) Hash() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return cellHash(h.top, h.left) * nodeHash(h.root)
+}
+
+func nodeHash(n *node) uint64 {
+	switch {
+	case n.population == 0:
+		return 0
+	case n.level == 0:
+		return 1
+	case n.hash != 0:
+		return n.hash
+	}
+	half := 1 << (n.level - 1)
+	down, right := pow(hashRow, half), pow(hashCol, half)
+	n.hash = nodeHash(n.nw) + right*nodeHash(n.ne) + down*(nodeHash(n.sw)+right*nodeHash(n.se))
+	return n.hash
+}
+
+func (h *Hashlife) Generation() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.generation
+}
+
+func (h *Hashlife) SetGeneration(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.generation = n
+}
+
+func (h *Hashlife) Topology() Topology {
+	return Infinite
+}
+
+func (h *Hashlife) Rule() Rule {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rule
+}
+
+// SetRule switches the rule, dropping every memoized result
+func (h *Hashlife) SetRule(rule Rule) error {
+	if rule.Birth[0] {
+		return ErrBirthOnZero
+	}
+	if rule.States > 2 {
+		return ErrGenerations
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.rule = rule
+	h.gc()
+	return nil
+}
+
+// Clear kills every cell and resets the generation count
+func (h *Hashlife) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.generation = 0
+	h.births, h.deaths = 0, 0
+	h.reset()
+}
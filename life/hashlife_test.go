@@ -0,0 +1,151 @@
+package life
+
+import (
+	"slices"
+	"testing"
+)
+
+// catalogPattern returns the built in object of the pattern library with
+// the given name
+func catalogPattern(t testing.TB, name string) *Pattern {
+	t.Helper()
+	for _, p := range Catalog() {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("no %s in the catalog", name)
+	return nil
+}
+
+// sameUniverse fails unless a and b have the same cells at the same place
+func sameUniverse(t *testing.T, a, b Universe) {
+	t.Helper()
+	as, bs := a.Stats(), b.Stats()
+	if as.Generation != bs.Generation || as.Population != bs.Population || as.X != bs.X || as.Y != bs.Y {
+		t.Fatalf("stats differ: %+v and %+v", as, bs)
+	}
+	if ap, bp := a.Pattern(), b.Pattern(); !slices.Equal(ap.Cells, bp.Cells) {
+		t.Fatalf("cells differ at generation %d", as.Generation)
+	}
+	if a.Hash() != b.Hash() {
+		t.Fatalf("hashes differ at generation %d: %#x and %#x", as.Generation, a.Hash(), b.Hash())
+	}
+}
+
+func TestHashlifeStepN(t *testing.T) {
+	tests := []struct {
+		pattern string
+		// Jumps taken one after the other
+		jumps []int
+		// Nodes kept before the memoized results are dropped, 0 for the default
+		maxNodes int
+	}{
+		{"Glider", []int{1, 1, 2, 4, 100}, 0},
+		{"R-pentomino", []int{1, 7, 64, 1000, 31}, 0},
+		{"Gosper glider gun", []int{30, 333, 512}, 0},
+		{"Acorn", []int{1023, 1}, 0},
+		{"Diehard", []int{129, 1, 1}, 0},
+		// Small enough that the table is dropped before every advance of a
+		// jump, with the results of the last one still in use
+		{"R-pentomino", []int{1, 7, 64, 1000, 31}, 64},
+		{"Acorn", []int{1023, 1}, 1},
+	}
+	for _, tt := range tests {
+		p := catalogPattern(t, tt.pattern)
+		h, err := NewHashlife(CONWAY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.maxNodes > 0 {
+			h.MaxNodes = tt.maxNodes
+		}
+		s, err := NewSparse(CONWAY)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.PlacePattern(p, -3, 5)
+		s.PlacePattern(p, -3, 5)
+		sameUniverse(t, h, s)
+		for _, n := range tt.jumps {
+			h.StepN(n)
+			s.StepN(n)
+			sameUniverse(t, h, s)
+		}
+	}
+}
+
+func TestHashlifeStep(t *testing.T) {
+	// Single steps count births and deaths like every other engine
+	h, err := NewHashlife(CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSparse(CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := catalogPattern(t, "R-pentomino")
+	h.PlacePattern(p, 0, 0)
+	s.PlacePattern(p, 0, 0)
+	for range 200 {
+		h.Step()
+		s.Step()
+		sameUniverse(t, h, s)
+		if hs, ss := h.Stats(), s.Stats(); hs.Births != ss.Births || hs.Deaths != ss.Deaths {
+			t.Fatalf("generation %d: %d births and %d deaths, want %d and %d", hs.Generation, hs.Births, hs.Deaths, ss.Births, ss.Deaths)
+		}
+	}
+}
+
+func TestHashAcrossEngines(t *testing.T) {
+	const height, width = 64, 80
+	engines := map[string]func() Universe{
+		"grid": func() Universe { return NewGrid(height, width, CONWAY) },
+		"bounded grid": func() Universe {
+			return NewBoundedGrid(height, width, CONWAY)
+		},
+		"bitgrid": func() Universe {
+			b, err := NewBitGrid(height, width, CONWAY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		},
+		"sparse": func() Universe {
+			s, err := NewSparse(CONWAY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+		"hashlife": func() Universe {
+			h, err := NewHashlife(CONWAY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return h
+		},
+	}
+	for _, name := range []string{"Glider", "Pulsar", "Gosper glider gun", "R-pentomino"} {
+		p := catalogPattern(t, name)
+		var want uint64
+		for _, gen := range []int{0, 1, 30} {
+			first := true
+			for engine, newUniverse := range engines {
+				u := newUniverse()
+				u.PlacePattern(p, 20, 17)
+				u.StepN(gen)
+				got := u.Hash()
+				if got == 0 {
+					t.Fatalf("%s on %s hashes to 0", name, engine)
+				}
+				if first {
+					want, first = got, false
+				} else if got != want {
+					t.Errorf("%s at generation %d hashes to %#x on %s, want %#x", name, gen, got, engine, want)
+				}
+			}
+		}
+	}
+}
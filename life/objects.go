@@ -0,0 +1,102 @@
+package life
+
+import (
+	"strings"
+	"sync"
+)
+
+// Common objects of B3/S23 soups, named by their apgcode
+var objectRLE = []string{
+	`#N Block
+x = 2, y = 2
+2o$2o!`,
+	`#N Beehive
+x = 4, y = 3
+b2o$o2bo$b2o!`,
+	`#N Loaf
+x = 4, y = 4
+b2o$o2bo$bobo$2bo!`,
+	`#N Boat
+x = 3, y = 3
+2o$obo$bo!`,
+	`#N Ship
+x = 3, y = 3
+2o$obo$b2o!`,
+	`#N Tub
+x = 3, y = 3
+bo$obo$bo!`,
+	`#N Pond
+x = 4, y = 4
+b2o$o2bo$o2bo$b2o!`,
+	`#N Long boat
+x = 4, y = 4
+2o$obo$bobo$2bo!`,
+	`#N Barge
+x = 4, y = 4
+bo$obo$bobo$2bo!`,
+	`#N Long barge
+x = 5, y = 5
+bo$obo$bobo$2bobo$3bo!`,
+	`#N Mango
+x = 5, y = 4
+b2o$o2bo$bo2bo$2b2o!`,
+	`#N Eater 1
+x = 4, y = 4
+2o$obo$2bo$2b2o!`,
+	`#N Aircraft carrier
+x = 4, y = 3
+2o$o2bo$2b2o!`,
+	`#N Snake
+x = 4, y = 2
+2obo$ob2o!`,
+	`#N Blinker
+x = 3, y = 1
+3o!`,
+	`#N Toad
+x = 4, y = 2
+b3o$3o!`,
+	`#N Beacon
+x = 4, y = 4
+2o$2o$2b2o$2b2o!`,
+	`#N Pulsar
+x = 13, y = 13
+2b3o3b3o2b2$o4bobo4bo$o4bobo4bo$o4bobo4bo$2b3o3b3o2b2$2b3o3b3o2b$o4bob
+o4bo$o4bobo4bo$o4bobo4bo2$2b3o3b3o!`,
+	`#N Pentadecathlon
+x = 10, y = 3
+2bo4bo2b$2ob4ob2o$2bo4bo!`,
+	`#N Glider
+x = 3, y = 3
+bo$2bo$3o!`,
+	`#N LWSS
+x = 5, y = 4
+bo2bo$o4b$o3bo$4o!`,
+	`#N MWSS
+x = 6, y = 5
+3bo2b$bo3bo$o5b$o4bo$5o!`,
+	`#N HWSS
+x = 7, y = 5
+3b2o2b$bo4bo$o6b$o5bo$6o!`,
+}
+
+var (
+	objectNames     map[string]string
+	objectNamesOnce sync.Once
+)
+
+// ObjectName returns the well known name of the B3/S23 object with the
+// given apgcode, or "" if it has none
+func ObjectName(code string) string {
+	objectNamesOnce.Do(func() {
+		objectNames = make(map[string]string, len(objectRLE))
+		for _, rle := range objectRLE {
+			p, err := ParseRLE(strings.NewReader(rle))
+			if err != nil {
+				panic(err)
+			}
+			o := classify(p.Cells, CONWAY)
+			objectNames[o.Code] = p.Name
+		}
+	})
+	return objectNames[code]
+}
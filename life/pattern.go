@@ -0,0 +1,177 @@
+package life
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type Point struct {
+	Row int
+	Col int
+}
+
+func comparePoints(a, b Point) int {
+	if a.Row != b.Row {
+		return cmp.Compare(a.Row, b.Row)
+	}
+	return cmp.Compare(a.Col, b.Col)
+}
+
+// Pattern is a rectangular block of cells read from or written to a pattern
+// file. Only the live cells are stored so that sparse patterns with huge
+// bounding boxes stay small.
+type Pattern struct {
+	Name     string
+	Comments []string
+	// Rule from the file header, nil if the file doesn't specify one
+	Rule   *Rule
+	Height int
+	Width  int
+	// Live cells relative to the top left corner, ordered by row then column
+	Cells []Point
+	// Dying cells of a Generations rule, ordered like Cells
+	Dying []DyingCell
+}
+
+// DyingCell is a cell of a Generations rule on its way from alive to dead
+type DyingCell struct {
+	Point
+	// 2 right after it died, up to the rule's States-1
+	State uint8
+}
+
+func NewPattern(height, width int) *Pattern {
+	return &Pattern{
+		Height: height,
+		Width:  width,
+	}
+}
+
+// Population returns the number of live cells in the pattern
+func (p *Pattern) Population() int {
+	return len(p.Cells)
+}
+
+// Alive reports whether the cell at row, col of the pattern is alive
+func (p *Pattern) Alive(row, col int) bool {
+	_, found := slices.BinarySearchFunc(p.Cells, Point{row, col}, comparePoints)
+	return found
+}
+
+// sortCells restores the row then column order of Cells and Dying
+func (p *Pattern) sortCells() {
+	slices.SortFunc(p.Cells, comparePoints)
+	slices.SortFunc(p.Dying, func(a, b DyingCell) int {
+		return comparePoints(a.Point, b.Point)
+	})
+}
+
+// isPlaintext reports whether path should use the Plaintext (.cells) format,
+// everything else is treated as RLE
+func isPlaintext(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".cells" || ext == ".txt"
+}
+
+// LoadPattern reads the pattern file at path, picking the format from its extension
+func LoadPattern(path string) (*Pattern, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parse := ParseRLE
+	if isPlaintext(path) {
+		parse = ParsePlaintext
+	}
+	p, err := parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ReadPattern reads a pattern that didn't come from a file, e.g. pasted text.
+// It's RLE if it has an "x = .." header and Plaintext otherwise.
+func ReadPattern(r io.Reader) (*Pattern, error) {
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		if line[0] == 'x' {
+			return ParseRLE(bytes.NewReader(text))
+		}
+		break
+	}
+	return ParsePlaintext(bytes.NewReader(text))
+}
+
+// SavePattern writes p to path as Plaintext if it ends in .cells, RLE otherwise
+func SavePattern(path string, p *Pattern) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	write := WriteRLE
+	if isPlaintext(path) {
+		write = WritePlaintext
+	}
+	if err := write(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// transform returns a copy of p with every cell moved by f and the given
+// size, the copy shares nothing with p
+func (p *Pattern) transform(height, width int, f func(Point) Point) *Pattern {
+	t := &Pattern{
+		Name:     p.Name,
+		Comments: slices.Clone(p.Comments),
+		Rule:     p.Rule,
+		Height:   height,
+		Width:    width,
+		Cells:    make([]Point, len(p.Cells)),
+	}
+	for i, c := range p.Cells {
+		t.Cells[i] = f(c)
+	}
+	for _, c := range p.Dying {
+		t.Dying = append(t.Dying, DyingCell{f(c.Point), c.State})
+	}
+	t.sortCells()
+	return t
+}
+
+// Rotate returns p turned 90° clockwise
+func (p *Pattern) Rotate() *Pattern {
+	return p.transform(p.Width, p.Height, func(c Point) Point {
+		return Point{c.Col, p.Height - 1 - c.Row}
+	})
+}
+
+// RotateCounter returns p turned 90° counterclockwise
+func (p *Pattern) RotateCounter() *Pattern {
+	return p.transform(p.Width, p.Height, func(c Point) Point {
+		return Point{p.Width - 1 - c.Col, c.Row}
+	})
+}
+
+// Mirror returns p flipped left to right
+func (p *Pattern) Mirror() *Pattern {
+	return p.transform(p.Height, p.Width, func(c Point) Point {
+		return Point{c.Row, p.Width - 1 - c.Col}
+	})
+}
@@ -0,0 +1,164 @@
+package life
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Preset fills, each covers the height x width region at the origin of u
+
+// region clips writes to the height x width region at the origin
+type region struct {
+	u             Universe
+	height, width int
+}
+
+func (r region) SetCell(x, y int, b bool) {
+	if x < 0 || x >= r.height || y < 0 || y >= r.width {
+		return
+	}
+	r.u.SetCell(x, y, b)
+}
+
+// Percentage of cells a random fill brings to life unless told otherwise
+const DEFAULT_DENSITY = 12.5
+
+// RandomFill brings density percent of the cells to life at random, the same
+// seed always gives the same cells
+func RandomFill(u Universe, height, width int, seed int64, density float64) {
+	Soup{Seed: seed, Density: density}.Fill(u, height, width)
+}
+
+// Soup is a reproducible random fill
+type Soup struct {
+	Seed int64
+	// Percentage of cells alive
+	Density float64
+	// Size of the soup, centered in the region filled. 0 fills all of it.
+	Height int
+	Width  int
+}
+
+// Fill kills every cell of the height x width region at the origin of u and
+// fills the soup in
+func (s Soup) Fill(u Universe, height, width int) {
+	r := region{u, height, width}
+	top, left, bottom, right := 0, 0, height, width
+	if s.Height > 0 && s.Width > 0 {
+		top, left = max(height-s.Height, 0)/2, max(width-s.Width, 0)/2
+		bottom, right = min(top+s.Height, height), min(left+s.Width, width)
+	}
+	rng := rand.New(rand.NewSource(s.Seed))
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			inside := i >= top && i < bottom && j >= left && j < right
+			r.SetCell(i, j, inside && rng.Float64()*100 < s.Density)
+		}
+	}
+}
+
+// String describes the soup well enough to fill it again, e.g. "seed 42,
+// density 50%, 16x16"
+func (s Soup) String() string {
+	str := fmt.Sprintf("seed %d, density %g%%", s.Seed, s.Density)
+	if s.Height > 0 && s.Width > 0 {
+		str += fmt.Sprintf(", %dx%d", s.Width, s.Height)
+	}
+	return str
+}
+
+func EdgeFill(u Universe, height, width int) {
+	r := region{u, height, width}
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			if i == 0 || i == height-1 || j == 0 || j == width-1 {
+				r.SetCell(i, j, true)
+			}
+		}
+	}
+}
+
+func PillarFill(u Universe, height, width int) {
+	r := region{u, height, width}
+	startP1 := (width / 3) - 1
+	startP2 := startP1 * 2
+	for i := 0; i < height; i++ {
+		for j := startP1; j <= startP1+3; j++ {
+			r.SetCell(i, j, true)
+		}
+		for j := startP2; j <= startP2+3; j++ {
+			r.SetCell(i, j, true)
+		}
+	}
+}
+
+func RowFill(u Universe, height, width int) {
+	r := region{u, height, width}
+	startP1 := (height / 3) - 1
+	startP2 := startP1 * 2
+	for j := 0; j < width; j++ {
+		for i := startP1; i <= startP1+3; i++ {
+			r.SetCell(i, j, true)
+		}
+		for i := startP2; i <= startP2+3; i++ {
+			r.SetCell(i, j, true)
+		}
+	}
+}
+
+func DottedLines(u Universe, height, width int) {
+	r := region{u, height, width}
+	for i := 0; i < height; i += 3 {
+		for j := 0; j < width; j += 3 {
+			alive := (i+j)%2 == 0
+			r.SetCell(i, j, alive)
+			r.SetCell(i, j+1, alive)
+			r.SetCell(i, j+2, alive)
+		}
+	}
+}
+
+func Threads(u Universe, height, width int) {
+	r := region{u, height, width}
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j += 3 {
+			alive := (i+j)%2 == 0
+			r.SetCell(i, j, alive)
+			r.SetCell(i, j+1, alive)
+			r.SetCell(i, j+2, alive)
+		}
+	}
+}
+
+func Checkerboard(u Universe, height, width int) {
+	r := region{u, height, width}
+	prev := true
+	for i := 0; i < height; i++ {
+		if i != 0 && i%4 == 0 {
+			prev = !prev
+		}
+		for j := 0; j < width; j += 4 {
+			r.SetCell(i, j, prev)
+			r.SetCell(i, j+1, prev)
+			r.SetCell(i, j+2, prev)
+			r.SetCell(i, j+3, prev)
+			prev = !prev
+		}
+	}
+}
+
+func Diamonds(u Universe, height, width, density int) {
+	r := region{u, height, width}
+	delta := max(height/density, 1)
+	for h := 0; h <= height; h += delta {
+		for j := 0; j < width; j++ {
+			for i := range delta {
+				r.SetCell(h+i, j, true)
+				r.SetCell(h+i, j+1, true)
+				r.SetCell(h+delta-1-i, j, true)
+				r.SetCell(h+delta-1-i, j+1, true)
+				j++
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package life
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseRLE reads a pattern in Run Length Encoded format:
+// https://conwaylife.com/wiki/Run_Length_Encoded
+func ParseRLE(r io.Reader) (*Pattern, error) {
+	var p *Pattern
+	var name string
+	var comments []string
+	row, col, count := 0, 0, 0
+	// Multi-state cells above 24 are two letters, 'p' to 'y' comes first
+	prefix := 0
+	lineNo := 0
+	done := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() && !done {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if p == nil {
+			switch {
+			case line == "":
+			case strings.HasPrefix(line, "#N"):
+				name = strings.TrimSpace(line[2:])
+			case strings.HasPrefix(line, "#C"), strings.HasPrefix(line, "#c"), strings.HasPrefix(line, "#O"):
+				comments = append(comments, strings.TrimSpace(line[2:]))
+			case strings.HasPrefix(line, "#"):
+				// #P/#R offsets and other extensions don't apply to a flat board
+			default:
+				var err error
+				p, err = parseRLEHeader(line)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				p.Name = name
+				p.Comments = comments
+			}
+			continue
+		}
+		for _, ch := range line {
+			switch {
+			case ch >= '0' && ch <= '9':
+				count = count*10 + int(ch-'0')
+				continue
+			case ch == ' ' || ch == '\t':
+				continue
+			case ch == '!':
+				done = true
+			case ch == '$':
+				row += max(count, 1)
+				col = 0
+			case ch == 'b' || ch == '.':
+				col += max(count, 1)
+			case ch >= 'p' && ch <= 'y':
+				prefix = int(ch-'p') + 1
+				continue
+			case ch == 'o' || (ch >= 'A' && ch <= 'Z'):
+				state := 1
+				if ch != 'o' {
+					state = prefix*24 + int(ch-'A') + 1
+				}
+				prefix = 0
+				n := max(count, 1)
+				if row >= p.Height || col+n > p.Width {
+					return nil, fmt.Errorf("line %d: live cells at row %d, column %d lie outside the %dx%d pattern", lineNo, row+1, col+n, p.Width, p.Height)
+				}
+				if state >= MAX_STATES {
+					return nil, fmt.Errorf("line %d: cell state %d is above %d", lineNo, state, MAX_STATES-1)
+				}
+				for range n {
+					if state == 1 {
+						p.Cells = append(p.Cells, Point{row, col})
+					} else {
+						p.Dying = append(p.Dying, DyingCell{Point{row, col}, uint8(state)})
+					}
+					col++
+				}
+			default:
+				return nil, fmt.Errorf("line %d: unexpected character %q", lineNo, ch)
+			}
+			if done {
+				break
+			}
+			count = 0
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("line %d: missing \"x = .., y = ..\" header", lineNo)
+	}
+	if count != 0 {
+		return nil, fmt.Errorf("line %d: run count %d is not followed by a cell", lineNo, count)
+	}
+	if p.Rule == nil || p.Rule.States == 0 {
+		// Multi-state files of other rule families, every state is alive
+		for _, c := range p.Dying {
+			p.Cells = append(p.Cells, c.Point)
+		}
+		p.Dying = nil
+		p.sortCells()
+	}
+	return p, nil
+}
+
+// parseRLEHeader parses a header line like "x = 3, y = 3, rule = B3/S23"
+func parseRLEHeader(line string) (*Pattern, error) {
+	width, height := -1, -1
+	var rule *Rule
+	key := ""
+	for field := range strings.SplitSeq(line, ",") {
+		k, value, ok := strings.Cut(field, "=")
+		if !ok && key == "rule" {
+			// The height of a topology suffix, "B3/S23:T100,100"
+			continue
+		} else if !ok {
+			return nil, fmt.Errorf("malformed header field %q, expected \"key = value\"", strings.TrimSpace(field))
+		}
+		key, value = strings.TrimSpace(k), strings.TrimSpace(value)
+		switch key {
+		case "x", "y":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("header %s = %q is not a valid size", key, value)
+			}
+			if key == "x" {
+				width = n
+			} else {
+				height = n
+			}
+		case "rule":
+			// Drop Golly's topology suffix, e.g. "B3/S23:T100,100"
+			value, _, _ = strings.Cut(value, ":")
+			r, err := ParseRule(value)
+			if err != nil {
+				return nil, err
+			}
+			rule = &r
+		}
+	}
+	if width < 0 || height < 0 {
+		return nil, fmt.Errorf("header %q must set both x and y", line)
+	}
+	p := NewPattern(height, width)
+	p.Rule = rule
+	return p, nil
+}
+
+// stateTag returns the multi-state RLE letters of a cell state
+func stateTag(state uint8) string {
+	s := int(state) - 1
+	if s < 24 {
+		return string(rune('A' + s))
+	}
+	return string([]rune{rune('p' + s/24 - 1), rune('A' + s%24)})
+}
+
+// WriteRLE writes p in Run Length Encoded format, rows are trimmed of
+// trailing dead cells and data lines are kept under 70 characters
+func WriteRLE(w io.Writer, p *Pattern) error {
+	bw := bufio.NewWriter(w)
+	if p.Name != "" {
+		fmt.Fprintf(bw, "#N %s\n", p.Name)
+	}
+	for _, c := range p.Comments {
+		fmt.Fprintf(bw, "#C %s\n", c)
+	}
+	rule := CONWAY
+	if p.Rule != nil {
+		rule = *p.Rule
+	}
+	fmt.Fprintf(bw, "x = %d, y = %d, rule = %s\n", p.Width, p.Height, rule)
+
+	var line strings.Builder
+	emit := func(n int, tag string) {
+		token := tag
+		if n > 1 {
+			token = strconv.Itoa(n) + token
+		}
+		if line.Len()+len(token) > 70 {
+			fmt.Fprintln(bw, line.String())
+			line.Reset()
+		}
+		line.WriteString(token)
+	}
+	// Dying cells need the multi-state letters, '.' dead, 'A' alive and 'B'
+	// onwards the dying states
+	dead, tag := "b", func(uint8) string { return "o" }
+	if len(p.Dying) > 0 {
+		dead, tag = ".", stateTag
+	}
+	cells := make([]DyingCell, 0, len(p.Cells)+len(p.Dying))
+	for _, c := range p.Cells {
+		cells = append(cells, DyingCell{c, 1})
+	}
+	cells = append(cells, p.Dying...)
+	slices.SortFunc(cells, func(a, b DyingCell) int {
+		return comparePoints(a.Point, b.Point)
+	})
+	row, col := 0, 0
+	for len(cells) > 0 {
+		c := cells[0]
+		if c.Row > row {
+			emit(c.Row-row, "$")
+			row, col = c.Row, 0
+		}
+		if c.Col > col {
+			emit(c.Col-col, dead)
+		}
+		run := 1
+		for run < len(cells) && cells[run].Row == c.Row && cells[run].Col == c.Col+run && cells[run].State == c.State {
+			run++
+		}
+		emit(run, tag(c.State))
+		col = c.Col + run
+		cells = cells[run:]
+	}
+	emit(1, "!")
+	fmt.Fprintln(bw, line.String())
+	return bw.Flush()
+}
@@ -0,0 +1,283 @@
+package life
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseRLE(t *testing.T) {
+	tests := []struct {
+		name     string
+		rle      string
+		height   int
+		width    int
+		cells    []Point
+		title    string
+		comments []string
+		rule     string
+	}{
+		{
+			name:   "glider",
+			rle:    "x = 3, y = 3\nbo$2bo$3o!",
+			height: 3, width: 3,
+			cells: []Point{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}},
+		},
+		{
+			name:   "name, comments and rule",
+			rle:    "#N Blinker\n#C Period 2\n#c lower case\n#O someone\n#P 1 1\nx = 3, y = 1, rule = B36/S23\n3o!",
+			height: 1, width: 3,
+			cells:    []Point{{0, 0}, {0, 1}, {0, 2}},
+			title:    "Blinker",
+			comments: []string{"Period 2", "lower case", "someone"},
+			rule:     "B36/S23",
+		},
+		{
+			name:   "header without spaces and Golly topology",
+			rle:    "x=2,y=2,rule=b3/s23:T10,10\n2o$2o!",
+			height: 2, width: 2,
+			cells: []Point{{0, 0}, {0, 1}, {1, 0}, {1, 1}},
+			rule:  "B3/S23",
+		},
+		{
+			name:   "runs of blank rows and multi digit counts",
+			rle:    "x = 12, y = 5\no11b$12o3$11bo!",
+			height: 5, width: 12,
+			cells: append(append([]Point{{0, 0}}, row(1, 0, 12)...), Point{4, 11}),
+		},
+		{
+			name:   "data wrapped across lines, a count split from its cell",
+			rle:    "x = 5, y = 2\n3\no2o\n$b\n o  b\no!",
+			height: 2, width: 5,
+			cells: []Point{{0, 0}, {0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 1}, {1, 3}},
+		},
+		{
+			name:   "everything after ! is ignored",
+			rle:    "x = 1, y = 1\no!\nthis is not RLE",
+			height: 1, width: 1,
+			cells: []Point{{0, 0}},
+		},
+		{
+			name:   "blank lines and CRLF",
+			rle:    "\r\n#N Dot\r\n\r\nx = 1, y = 1\r\no!\r\n",
+			height: 1, width: 1,
+			cells: []Point{{0, 0}},
+			title: "Dot",
+		},
+		{
+			name:   "empty pattern",
+			rle:    "x = 0, y = 0\n!",
+			height: 0, width: 0,
+		},
+		{
+			name:   "multi-state cells of another rule family are alive",
+			rle:    "x = 3, y = 1\nA.B!",
+			height: 1, width: 3,
+			cells: []Point{{0, 0}, {0, 2}},
+		},
+	}
+	for _, tt := range tests {
+		p, err := ParseRLE(strings.NewReader(tt.rle))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if p.Height != tt.height || p.Width != tt.width {
+			t.Errorf("%s: size %dx%d, want %dx%d", tt.name, p.Width, p.Height, tt.width, tt.height)
+		}
+		if !slices.Equal(p.Cells, tt.cells) {
+			t.Errorf("%s: cells %v, want %v", tt.name, p.Cells, tt.cells)
+		}
+		if p.Name != tt.title || !slices.Equal(p.Comments, tt.comments) {
+			t.Errorf("%s: name %q and comments %q, want %q and %q", tt.name, p.Name, p.Comments, tt.title, tt.comments)
+		}
+		rule := ""
+		if p.Rule != nil {
+			rule = p.Rule.String()
+		}
+		if rule != tt.rule {
+			t.Errorf("%s: rule %q, want %q", tt.name, rule, tt.rule)
+		}
+	}
+}
+
+func TestParseRLEGenerations(t *testing.T) {
+	p, err := ParseRLE(strings.NewReader("x = 4, y = 1, rule = /2/3\nA.2B!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DyingCell{{Point{0, 2}, 2}, {Point{0, 3}, 2}}
+	if !slices.Equal(p.Cells, []Point{{0, 0}}) || !slices.Equal(p.Dying, want) {
+		t.Errorf("cells %v and dying %v, want [{0 0}] and %v", p.Cells, p.Dying, want)
+	}
+}
+
+func TestParseRLEErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rle  string
+		// Part of the error
+		err string
+	}{
+		{"no header", "#N Nothing\n", "line 1: missing \"x = .., y = ..\" header"},
+		{"empty", "", "missing \"x = .., y = ..\" header"},
+		{"header without y", "x = 3\n3o!", "line 1: header \"x = 3\" must set both x and y"},
+		{"malformed field", "x = 3, y\n3o!", "malformed header field \"y\""},
+		{"negative size", "x = -1, y = 2\n!", "header x = \"-1\" is not a valid size"},
+		{"not a number", "x = three, y = 1\n3o!", "header x = \"three\""},
+		{"invalid rule", "x = 1, y = 1, rule = B9/S23\no!", "neighbor count '9' is not in 0-8"},
+		{"row too long", "x = 2, y = 1\n3o!", "line 2: live cells at row 1, column 3 lie outside the 2x1 pattern"},
+		{"too many rows", "x = 1, y = 1\no$o!", "line 2: live cells at row 2"},
+		{"wrapped past the edge", "x = 2, y = 2\no\nb\no!", "line 4: live cells at row 1, column 3"},
+		{"unexpected character", "x = 2, y = 1\noz!", "line 2: unexpected character 'z'"},
+		{"dangling count", "x = 3, y = 1\n2o3", "run count 3 is not followed by a cell"},
+	}
+	for _, tt := range tests {
+		_, err := ParseRLE(strings.NewReader(tt.rle))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+// row returns the cells of a horizontal line of n cells
+func row(r, c, n int) []Point {
+	cells := make([]Point, n)
+	for i := range cells {
+		cells[i] = Point{r, c + i}
+	}
+	return cells
+}
+
+func TestWriteRLE(t *testing.T) {
+	highlife, brain := MustParseRule("B36/S23"), MustParseRule("/2/3")
+	tests := []struct {
+		name string
+		p    *Pattern
+		want string
+	}{
+		{
+			name: "glider",
+			p:    &Pattern{Height: 3, Width: 3, Cells: []Point{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}},
+			want: "x = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n",
+		},
+		{
+			name: "name, comments, rule and blank rows",
+			p: &Pattern{
+				Name: "Blinkers", Comments: []string{"Two of them"}, Rule: &highlife,
+				Height: 5, Width: 3, Cells: append(row(0, 0, 3), row(4, 0, 3)...),
+			},
+			want: "#N Blinkers\n#C Two of them\nx = 3, y = 5, rule = B36/S23\n3o4$3o!\n",
+		},
+		{
+			name: "trailing dead cells and rows are trimmed",
+			p:    &Pattern{Height: 4, Width: 6, Cells: []Point{{1, 1}}},
+			want: "x = 6, y = 4, rule = B3/S23\n$bo!\n",
+		},
+		{
+			name: "empty",
+			p:    &Pattern{Height: 2, Width: 2},
+			want: "x = 2, y = 2, rule = B3/S23\n!\n",
+		},
+		{
+			name: "dying cells",
+			p: &Pattern{
+				Rule: &brain, Height: 1, Width: 4,
+				Cells: []Point{{0, 0}}, Dying: []DyingCell{{Point{0, 2}, 2}, {Point{0, 3}, 2}},
+			},
+			want: "x = 4, y = 1, rule = B2/S/C3\nA.2B!\n",
+		},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		if err := WriteRLE(&sb, tt.p); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != tt.want {
+			t.Errorf("%s: wrote\n%s\nwant\n%s", tt.name, sb.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteRLELineWrap(t *testing.T) {
+	// Alternating cells don't run together, so every token is 1 character
+	p := &Pattern{Height: 3, Width: 200}
+	for r := range 3 {
+		for c := 0; c < 200; c += 2 {
+			p.Cells = append(p.Cells, Point{r, c})
+		}
+	}
+	var sb strings.Builder
+	if err := WriteRLE(&sb, p); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	for i, line := range lines[1:] {
+		if len(line) > 70 {
+			t.Errorf("line %d is %d characters long", i+2, len(line))
+		}
+		if i < len(lines)-2 && len(line) < 69 {
+			t.Errorf("line %d is only %d characters long", i+2, len(line))
+		}
+	}
+	back, err := ParseRLE(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(back.Cells, p.Cells) {
+		t.Errorf("wrapped lines read back as other cells")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(*strings.Builder, *Pattern) error
+		parse func(string) (*Pattern, error)
+	}{
+		{
+			"RLE",
+			func(sb *strings.Builder, p *Pattern) error { return WriteRLE(sb, p) },
+			func(s string) (*Pattern, error) { return ParseRLE(strings.NewReader(s)) },
+		},
+		{
+			"Plaintext",
+			func(sb *strings.Builder, p *Pattern) error { return WritePlaintext(sb, p) },
+			func(s string) (*Pattern, error) { return ParsePlaintext(strings.NewReader(s)) },
+		},
+		{
+			"RLE or Plaintext",
+			func(sb *strings.Builder, p *Pattern) error { return WriteRLE(sb, p) },
+			func(s string) (*Pattern, error) { return ReadPattern(strings.NewReader(s)) },
+		},
+		{
+			"Plaintext or RLE",
+			func(sb *strings.Builder, p *Pattern) error { return WritePlaintext(sb, p) },
+			func(s string) (*Pattern, error) { return ReadPattern(strings.NewReader(s)) },
+		},
+	}
+	for _, f := range formats {
+		for _, p := range Catalog() {
+			p.Comments = append(p.Comments, "Saved by a test")
+			var sb strings.Builder
+			if err := f.write(&sb, p); err != nil {
+				t.Fatal(err)
+			}
+			back, err := f.parse(sb.String())
+			if err != nil {
+				t.Errorf("%s of %s: %v", f.name, p.Name, err)
+				continue
+			}
+			if back.Name != p.Name || !slices.Equal(back.Comments, p.Comments) || *back.Rule != *p.Rule {
+				t.Errorf("%s of %s: read back as %q %q %v", f.name, p.Name, back.Name, back.Comments, back.Rule)
+			}
+			if !slices.Equal(back.Cells, p.Cells) {
+				t.Errorf("%s of %s: read back as other cells", f.name, p.Name)
+			}
+			// Plaintext has no size, trailing dead rows and columns are lost
+			if f.name == "RLE" && (back.Height != p.Height || back.Width != p.Width) {
+				t.Errorf("%s of %s: read back as %dx%d, want %dx%d", f.name, p.Name, back.Width, back.Height, p.Width, p.Height)
+			}
+		}
+	}
+}
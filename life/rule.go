@@ -0,0 +1,157 @@
+package life
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rule is a Life-like (outer totalistic) rule: Birth[n] says whether a dead
+// cell with n live neighbors is born, Survive[n] whether a live one stays alive.
+//
+// Generations rules add dying states: a live cell that doesn't survive goes
+// through states 2 to States-1, one per generation, before it's dead. Dying
+// cells can't be born and don't count as neighbors.
+type Rule struct {
+	Birth   [9]bool
+	Survive [9]bool
+	// Number of cell states of a Generations rule, 0 for Life-like rules
+	States int
+}
+
+// Most states a Generations rule can have, a cell's state is a byte
+const MAX_STATES = 256
+
+type NamedRule struct {
+	Name string
+	Rule Rule
+}
+
+var (
+	CONWAY = MustParseRule("B3/S23")
+	// Rule choices offered in the rule picker
+	RULES = []NamedRule{
+		{"Conway's Life", CONWAY},
+		{"HighLife", MustParseRule("B36/S23")},
+		{"Seeds", MustParseRule("B2/S")},
+		{"Day & Night", MustParseRule("B3678/S34678")},
+		{"Morley", MustParseRule("B368/S245")},
+		{"Life without Death", MustParseRule("B3/S012345678")},
+		{"2x2", MustParseRule("B36/S125")},
+		{"Replicator", MustParseRule("B1357/S1357")},
+		{"Maze", MustParseRule("B3/S12345")},
+		{"Diamoeba", MustParseRule("B35678/S5678")},
+		{"Brian's Brain", MustParseRule("/2/3")},
+		{"Star Wars", MustParseRule("345/2/4")},
+	}
+)
+
+// ParseRule parses a rulestring in B/S notation ("B36/S23") or in the
+// traditional S/B notation ("23/36"). Generations rules have the number of
+// states as a third part, "B2/S/C3" or "/2/3". Letters are case insensitive.
+func ParseRule(s string) (Rule, error) {
+	var r Rule
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) != 2 && len(parts) != 3 {
+		return r, fmt.Errorf("invalid rule %q: expected two parts separated by '/', e.g. B3/S23, or three for Generations rules, e.g. B2/S/C3", s)
+	}
+	var haveB, haveS, lettered bool
+	for i, part := range parts[:2] {
+		var counts *[9]bool
+		digits := part
+		switch {
+		case strings.HasPrefix(part, "B"), strings.HasPrefix(part, "b"):
+			if haveB {
+				return r, fmt.Errorf("invalid rule %q: birth conditions given twice", s)
+			}
+			haveB, lettered = true, true
+			counts, digits = &r.Birth, part[1:]
+		case strings.HasPrefix(part, "S"), strings.HasPrefix(part, "s"):
+			if haveS {
+				return r, fmt.Errorf("invalid rule %q: survival conditions given twice", s)
+			}
+			haveS, lettered = true, true
+			counts, digits = &r.Survive, part[1:]
+		case lettered:
+			return r, fmt.Errorf("invalid rule %q: %q must start with B or S", s, part)
+		case i == 0:
+			// S/B notation: survival counts come first
+			haveS = true
+			counts = &r.Survive
+		default:
+			haveB = true
+			counts = &r.Birth
+		}
+		for _, d := range digits {
+			if d < '0' || d > '8' {
+				return r, fmt.Errorf("invalid rule %q: neighbor count %q is not in 0-8", s, d)
+			}
+			counts[d-'0'] = true
+		}
+	}
+	if !haveB || !haveS {
+		return r, fmt.Errorf("invalid rule %q: needs both a B and an S part", s)
+	}
+	if len(parts) == 3 {
+		states := parts[2]
+		if lettered {
+			if !strings.HasPrefix(strings.ToUpper(states), "C") && !strings.HasPrefix(strings.ToUpper(states), "G") {
+				return r, fmt.Errorf("invalid rule %q: %q must start with C", s, states)
+			}
+			states = states[1:]
+		}
+		n, err := strconv.Atoi(states)
+		if err != nil || n < 2 || n > MAX_STATES {
+			return r, fmt.Errorf("invalid rule %q: number of states %q is not in 2-%d", s, states, MAX_STATES)
+		}
+		if n > 2 {
+			r.States = n
+		}
+	}
+	return r, nil
+}
+
+// NumStates returns how many states a cell can be in, 2 for Life-like rules
+func (r Rule) NumStates() int {
+	return max(r.States, 2)
+}
+
+// MustParseRule is like ParseRule but panics on invalid rules
+func MustParseRule(s string) Rule {
+	r, err := ParseRule(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// String returns the rule in canonical B/S notation, e.g. "B3/S23".
+func (r Rule) String() string {
+	var sb strings.Builder
+	sb.WriteByte('B')
+	for n, b := range r.Birth {
+		if b {
+			sb.WriteByte(byte('0' + n))
+		}
+	}
+	sb.WriteString("/S")
+	for n, s := range r.Survive {
+		if s {
+			sb.WriteByte(byte('0' + n))
+		}
+	}
+	if r.States > 2 {
+		fmt.Fprintf(&sb, "/C%d", r.States)
+	}
+	return sb.String()
+}
+
+// Name returns the well known name of the rule, or "" if it has none.
+func (r Rule) Name() string {
+	for _, nr := range RULES {
+		if nr.Rule == r {
+			return nr.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+package life
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		// Canonical B/S notation
+		want string
+	}{
+		{"B3/S23", "B3/S23"},
+		{"b3/s23", "B3/S23"},
+		{"B3/s23", "B3/S23"},
+		{"  B36/S23 ", "B36/S23"},
+		{"S23/B3", "B3/S23"},
+		{"s23/b36", "B36/S23"},
+		// S/B notation, survival first
+		{"23/3", "B3/S23"},
+		{"23/36", "B36/S23"},
+		{"/2", "B2/S"},
+		{"012345678/3", "B3/S012345678"},
+		{"B2/S", "B2/S"},
+		{"B/S", "B/S"},
+		{"B0/S8", "B0/S8"},
+		// Digits in any order, repeats allowed
+		{"B63/S3223", "B36/S23"},
+		// Generations rules
+		{"B2/S/C3", "B2/S/C3"},
+		{"b2/s/c3", "B2/S/C3"},
+		{"B2/S/G3", "B2/S/C3"},
+		{"/2/3", "B2/S/C3"},
+		{"345/2/4", "B2/S345/C4"},
+		{"B3/S23/C2", "B3/S23"},
+		{"B3/S23/C256", "B3/S23/C256"},
+	}
+	for _, tt := range tests {
+		r, err := ParseRule(tt.rule)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.rule, err)
+			continue
+		}
+		if r.String() != tt.want {
+			t.Errorf("ParseRule(%q) = %s, want %s", tt.rule, r, tt.want)
+		}
+		again, err := ParseRule(r.String())
+		if err != nil || again != r {
+			t.Errorf("ParseRule(%q) = %v, %v, want %v", r.String(), again, err, r)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		// Part of the error
+		err string
+	}{
+		{"", "expected two parts separated by '/'"},
+		{"B3S23", "expected two parts separated by '/'"},
+		{"B3/S23/C3/X", "expected two parts separated by '/'"},
+		{"B9/S23", "neighbor count '9' is not in 0-8"},
+		{"B3/S2a", "neighbor count 'a' is not in 0-8"},
+		{"B3/B6", "birth conditions given twice"},
+		{"S23/s2", "survival conditions given twice"},
+		{"B3/23", `"23" must start with B or S`},
+		{"S23/3", `"3" must start with B or S`},
+		{"B3/S23/3", `"3" must start with C`},
+		{"/2/C3", `number of states "C3" is not in 2-256`},
+		{"B2/S/C1", `number of states "1" is not in 2-256`},
+		{"B2/S/C257", `number of states "257" is not in 2-256`},
+		{"B2/S/Cx", `number of states "x" is not in 2-256`},
+	}
+	for _, tt := range tests {
+		_, err := ParseRule(tt.rule)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("ParseRule(%q): error %v, want one containing %q", tt.rule, err, tt.err)
+		}
+	}
+}
+
+func TestRuleName(t *testing.T) {
+	for _, nr := range RULES {
+		if nr.Rule.Name() != nr.Name {
+			t.Errorf("%s is named %q, want %q", nr.Rule, nr.Rule.Name(), nr.Name)
+		}
+	}
+	if name := MustParseRule("B1/S1").Name(); name != "" {
+		t.Errorf("B1/S1 is named %q", name)
+	}
+}
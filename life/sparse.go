@@ -0,0 +1,290 @@
+package life
+
+import (
+	"sync"
+)
+
+// Sparse is an unbounded Life universe that stores only its live cells, so
+// memory and time per generation grow with the population instead of the
+// area. It is safe for concurrent use.
+type Sparse struct {
+	mu    sync.Mutex
+	rule  Rule
+	cells map[Point]struct{}
+	// Dying cells of a Generations rule and their state
+	dying map[Point]uint8
+	// Generations stepped since the universe was created or cleared
+	generation int
+	// Cells born and died in the last step
+	births int
+	deaths int
+}
+
+// NewSparse returns an empty unbounded universe that evolves under rule
+func NewSparse(rule Rule) (*Sparse, error) {
+	if rule.Birth[0] {
+		return nil, ErrBirthOnZero
+	}
+	return &Sparse{
+		rule:  rule,
+		cells: make(map[Point]struct{}),
+		dying: make(map[Point]uint8),
+	}, nil
+}
+
+// Step advances the universe by one generation
+func (s *Sparse) Step() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[Point]int, len(s.cells)*4)
+	for p := range s.cells {
+		for dr := -1; dr <= 1; dr++ {
+			for dc := -1; dc <= 1; dc++ {
+				if dr != 0 || dc != 0 {
+					counts[Point{p.Row + dr, p.Col + dc}]++
+				}
+			}
+		}
+	}
+	next := make(map[Point]struct{}, len(s.cells))
+	births := 0
+	for p, n := range counts {
+		_, alive := s.cells[p]
+		_, dying := s.dying[p]
+		if alive && s.rule.Survive[n] || !alive && !dying && s.rule.Birth[n] {
+			next[p] = struct{}{}
+			if !alive {
+				births++
+			}
+		}
+	}
+	// Isolated cells never show up in counts
+	if s.rule.Survive[0] {
+		for p := range s.cells {
+			if _, ok := counts[p]; !ok {
+				next[p] = struct{}{}
+			}
+		}
+	}
+	dying := make(map[Point]uint8, len(s.dying))
+	last := uint8(s.rule.NumStates() - 1)
+	for p, state := range s.dying {
+		if state < last {
+			dying[p] = state + 1
+		}
+	}
+	if last > 1 {
+		for p := range s.cells {
+			if _, ok := next[p]; !ok {
+				dying[p] = 2
+			}
+		}
+	}
+	s.births = births
+	s.deaths = len(s.cells) - (len(next) - births)
+	s.cells = next
+	s.dying = dying
+	s.generation++
+}
+
+// StepN advances the universe by n generations
+func (s *Sparse) StepN(n int) {
+	for range n {
+		s.Step()
+	}
+}
+
+func (s *Sparse) Cell(row, col int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, alive := s.cells[Point{row, col}]
+	return alive
+}
+
+func (s *Sparse) SetCell(row, col int, alive bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.dying, Point{row, col})
+	if alive {
+		s.cells[Point{row, col}] = struct{}{}
+	} else {
+		delete(s.cells, Point{row, col})
+	}
+}
+
+func (s *Sparse) Region(top, left, height, width int) [][]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cells := newRegion(height, width)
+	// Walk whichever is smaller, the live cells or the region
+	if len(s.cells) < height*width {
+		for p := range s.cells {
+			if p.Row >= top && p.Row < top+height && p.Col >= left && p.Col < left+width {
+				cells[p.Row-top][p.Col-left] = true
+			}
+		}
+		return cells
+	}
+	for i := range height {
+		for j := range width {
+			_, cells[i][j] = s.cells[Point{top + i, left + j}]
+		}
+	}
+	return cells
+}
+
+// RegionStates looks up every cell of the region, unlike Region it doesn't
+// walk the live cells when there are fewer of them
+func (s *Sparse) RegionStates(top, left, height, width int) [][]uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cells := newStates(height, width)
+	for i := range height {
+		for j := range width {
+			p := Point{top + i, left + j}
+			if _, alive := s.cells[p]; alive {
+				cells[i][j] = 1
+			} else {
+				cells[i][j] = s.dying[p]
+			}
+		}
+	}
+	return cells
+}
+
+func (s *Sparse) PlacePattern(p *Pattern, row, col int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range p.Cells {
+		s.cells[Point{row + c.Row, col + c.Col}] = struct{}{}
+		delete(s.dying, Point{row + c.Row, col + c.Col})
+	}
+	last := s.rule.NumStates() - 1
+	for _, c := range p.Dying {
+		if int(c.State) <= last {
+			delete(s.cells, Point{row + c.Row, col + c.Col})
+			s.dying[Point{row + c.Row, col + c.Col}] = c.State
+		}
+	}
+}
+
+// bounds returns the bounding box of the live and dying cells, the box is
+// empty when there are none
+func (s *Sparse) bounds() (top, left, height, width int) {
+	if len(s.cells) == 0 && len(s.dying) == 0 {
+		return 0, 0, 0, 0
+	}
+	first := true
+	var bottom, right int
+	grow := func(p Point) {
+		if first {
+			top, left, bottom, right = p.Row, p.Col, p.Row, p.Col
+			first = false
+			return
+		}
+		top, bottom = min(top, p.Row), max(bottom, p.Row)
+		left, right = min(left, p.Col), max(right, p.Col)
+	}
+	for p := range s.cells {
+		grow(p)
+	}
+	for p := range s.dying {
+		grow(p)
+	}
+	return top, left, bottom - top + 1, right - left + 1
+}
+
+func (s *Sparse) Pattern() *Pattern {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	top, left, height, width := s.bounds()
+	rule := s.rule
+	p := NewPattern(height, width)
+	p.Rule = &rule
+	p.Cells = make([]Point, 0, len(s.cells))
+	for c := range s.cells {
+		p.Cells = append(p.Cells, Point{c.Row - top, c.Col - left})
+	}
+	for c, state := range s.dying {
+		p.Dying = append(p.Dying, DyingCell{Point{c.Row - top, c.Col - left}, state})
+	}
+	p.sortCells()
+	return p
+}
+
+func (s *Sparse) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	top, left, height, width := s.bounds()
+	return Stats{
+		Generation: s.generation,
+		Population: len(s.cells),
+		X:          left,
+		Y:          top,
+		Width:      width,
+		Height:     height,
+		Births:     s.births,
+		Deaths:     s.deaths,
+	}
+}
+
+func (s *Sparse) Hash() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sum uint64
+	for p := range s.cells {
+		sum += cellHash(p.Row, p.Col)
+	}
+	for p, state := range s.dying {
+		sum += cellHash(p.Row, p.Col) * uint64(state)
+	}
+	return sum
+}
+
+func (s *Sparse) Generation() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+func (s *Sparse) SetGeneration(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation = n
+}
+
+func (s *Sparse) Topology() Topology {
+	return Infinite
+}
+
+func (s *Sparse) Rule() Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rule
+}
+
+func (s *Sparse) SetRule(rule Rule) error {
+	if rule.Birth[0] {
+		return ErrBirthOnZero
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rule = rule
+	last := uint8(rule.NumStates() - 1)
+	for p, state := range s.dying {
+		if state > last {
+			delete(s.dying, p)
+		}
+	}
+	return nil
+}
+
+// Clear kills every cell and resets the generation count
+func (s *Sparse) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation = 0
+	s.births, s.deaths = 0, 0
+	s.cells = make(map[Point]struct{})
+	s.dying = make(map[Point]uint8)
+}
@@ -0,0 +1,99 @@
+package life
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrBirthOnZero = errors.New("rules with B0 can't be simulated on an unbounded plane, empty space would come alive")
+	ErrGenerations = errors.New("hashlife and bitgrid can't simulate Generations rules, the grid and sparse engines can")
+)
+
+// Topology is the shape of the space a Universe lives in
+type Topology int
+
+const (
+	// Fixed size, the edges wrap around
+	Torus Topology = iota
+	// Fixed size, everything past the edges is dead
+	Bounded
+	// Unbounded plane
+	Infinite
+)
+
+func (t Topology) String() string {
+	switch t {
+	case Torus:
+		return "torus"
+	case Bounded:
+		return "bounded"
+	case Infinite:
+		return "infinite"
+	}
+	return fmt.Sprintf("Topology(%d)", int(t))
+}
+
+// ParseTopology parses the name of a topology as returned by String
+func ParseTopology(s string) (Topology, error) {
+	for _, t := range []Topology{Torus, Bounded, Infinite} {
+		if s == t.String() {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown topology %q, expected torus, bounded or infinite", s)
+}
+
+// Universe is a Life board that can be edited and stepped. Cells are
+// addressed by row and column, see Topology for what lies past the edges.
+type Universe interface {
+	Cell(row, col int) bool
+	SetCell(row, col int, alive bool)
+	// Region copies the height x width block of cells at top, left
+	Region(top, left, height, width int) [][]bool
+	// RegionStates is Region with the state of every cell, 0 is dead, 1
+	// alive and higher states are dying under a Generations rule
+	RegionStates(top, left, height, width int) [][]uint8
+	// PlacePattern sets the live cells of p with its top left corner at row, col
+	PlacePattern(p *Pattern, row, col int)
+	// Pattern returns the live cells cropped to their bounding box
+	Pattern() *Pattern
+	Step()
+	StepN(n int)
+	Generation() int
+	// SetGeneration sets the generation count, e.g. when going back in time
+	SetGeneration(n int)
+	Stats() Stats
+	// Hash identifies the live cells and where they are, equal universes
+	// hash the same whatever their engine
+	Hash() uint64
+	Topology() Topology
+	Rule() Rule
+	// SetRule fails if the universe can't simulate rule
+	SetRule(rule Rule) error
+	Clear()
+}
+
+// Finite is a Universe of a fixed size, a torus or a bounded map
+type Finite interface {
+	Universe
+	Size() (height, width int)
+	// Resize grows the map to at least height x width, it never shrinks
+	Resize(height, width int)
+}
+
+func newStates(height, width int) [][]uint8 {
+	cells := make([][]uint8, height)
+	for i := range cells {
+		cells[i] = make([]uint8, width)
+	}
+	return cells
+}
+
+func newRegion(height, width int) [][]bool {
+	cells := make([][]bool, height)
+	for i := range cells {
+		cells[i] = make([]bool, width)
+	}
+	return cells
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"golang.org/x/term"
+
+	"github.com/Cybergenik/cgl/life"
 )
 
 const (
@@ -15,270 +20,114 @@ const (
 	DEFAULT_WIDTH  = 160
 )
 
+// CGL drives a life.Universe for the TUI, the next generation is computed in
+// the background while the current one is on screen
 type CGL struct {
-	mu       sync.Mutex
-	gameMap  [][]bool
-	updateCh chan struct{}
-	height   int
-	width    int
+	life.Universe
+	// Each frame the UI hands the game loop a channel to close once the
+	// next generation is ready
+	updateCh chan chan struct{}
+	loop     sync.Once
+	// Closed once the last generation asked for is ready
+	stepping <-chan struct{}
+	// The map size was given with -size and doesn't follow the terminal
+	fixed bool
+	// Generations the game loop stepped through
+	Rewind *Rewind
 }
 
-func initCGL(height, width int) *CGL {
-	cgl := CGL{
-		gameMap:  make([][]bool, height),
-		updateCh: make(chan struct{}),
-		height:   height,
-		width:    width,
-	}
-	for i := 0; i < cgl.height; i++ {
-		cgl.gameMap[i] = make([]bool, cgl.width)
+func initCGL(universe life.Universe) *CGL {
+	return &CGL{
+		Universe: universe,
+		updateCh: make(chan chan struct{}),
+		Rewind:   NewRewind(DEFAULT_REWIND),
 	}
-	return &cgl
 }
 
-func (cgl *CGL) neighbors(gameMap [][]bool, r int, c int) int {
-	total := 0
-	var adr, bdr, dc int
-	if r > 0 {
-		adr = r - 1
-	} else {
-		adr = cgl.height - 1
-	}
-	bdr = (r + 1) % cgl.height
-	if c > 0 {
-		dc = c - 1
-	} else {
-		dc = cgl.width - 1
-	}
-	if gameMap[r][dc] {
-		total += 1
-	}
-	for range 3 {
-		if gameMap[adr][dc] {
-			total += 1
+// newUniverse builds the simulation engine named by the -engine and
+// -topology flags, an empty topology picks the engine's default
+func newUniverse(engine, topology string, height, width int, rule life.Rule) (life.Universe, error) {
+	switch engine {
+	case "grid":
+		if topology == "" {
+			topology = life.Torus.String()
 		}
-		if gameMap[bdr][dc] {
-			total += 1
+		t, err := life.ParseTopology(topology)
+		if err != nil {
+			return nil, err
 		}
-		dc = (dc + 1) % cgl.width
-	}
-	if gameMap[r][(c+1)%cgl.width] {
-		total += 1
-	}
-	return total
-}
-
-func (cgl *CGL) gameLoop() {
-	for {
-		cgl.mu.Lock()
-		curr_map := make([][]bool, cgl.height)
-		for i := range cgl.gameMap {
-			curr_map[i] = make([]bool, cgl.width)
-			copy(curr_map[i], cgl.gameMap[i])
+		switch t {
+		case life.Torus:
+			return life.NewGrid(height, width, rule), nil
+		case life.Bounded:
+			return life.NewBoundedGrid(height, width, rule), nil
+		default:
+			return life.NewSparse(rule)
 		}
-		for r := 0; r < cgl.height; r++ {
-			for c := 0; c < cgl.width; c++ {
-				n := cgl.neighbors(curr_map, r, c)
-				//Live cell
-				if curr_map[r][c] {
-					if n < 2 || n > 3 {
-						cgl.gameMap[r][c] = false
-					}
-					//Dead cell
-				} else {
-					if n == 3 {
-						cgl.gameMap[r][c] = true
-					}
-				}
-			}
+	case "bitgrid":
+		if topology == "" {
+			topology = life.Torus.String()
 		}
-		cgl.mu.Unlock()
-		<-cgl.updateCh
-	}
-}
-
-func (cgl *CGL) RandomFill() {
-	for i := 0; i < cgl.height; i++ {
-		for j := 0; j < cgl.width; j++ {
-			v := rand.Intn(8) // 1/8 chance to alive
-			if v == 0 {
-				cgl.gameMap[i][j] = true
-			} else {
-				cgl.gameMap[i][j] = false
-			}
+		t, err := life.ParseTopology(topology)
+		if err != nil {
+			return nil, err
 		}
-	}
-}
-
-func (cgl *CGL) EdgeFill() {
-	for i := 0; i < cgl.height; i++ {
-		for j := 0; j < cgl.width; j++ {
-			if i == 0 || i == cgl.height-1 || j == 0 || j == cgl.width-1 {
-				cgl.gameMap[i][j] = true
-			}
+		switch t {
+		case life.Torus:
+			return life.NewBitGrid(height, width, rule)
+		case life.Bounded:
+			return life.NewBoundedBitGrid(height, width, rule)
 		}
-	}
-}
-
-func (cgl *CGL) PillarFill() {
-	startP1 := (cgl.width / 3) - 1
-	startP2 := startP1 * 2
-	for i := 0; i < cgl.height; i++ {
-		for j := startP1; j <= startP1+3; j++ {
-			cgl.gameMap[i][j] = true
-		}
-		for j := startP2; j <= startP2+3; j++ {
-			cgl.gameMap[i][j] = true
+		return nil, fmt.Errorf("bitgrid only runs on the torus and bounded topologies")
+	case "hashlife":
+		if topology != "" && topology != life.Infinite.String() {
+			return nil, fmt.Errorf("hashlife only runs on the infinite topology")
 		}
+		return life.NewHashlife(rule)
 	}
+	return nil, fmt.Errorf("unknown engine %q, expected grid, bitgrid or hashlife", engine)
 }
 
-func (cgl *CGL) RowFill() {
-	startP1 := (cgl.height / 3) - 1
-	startP2 := startP1 * 2
-	for j := 0; j < cgl.width; j++ {
-		for i := startP1; i <= startP1+3; i++ {
-			cgl.gameMap[i][j] = true
-		}
-		for i := startP2; i <= startP2+3; i++ {
-			cgl.gameMap[i][j] = true
-		}
-	}
-}
-
-func (cgl *CGL) DottedLines() {
-	for i := 0; i < cgl.height; i += 3 {
-		for j := 0; j < cgl.width; j += 3 {
-			if (i+j)%2 == 0 {
-				cgl.SetCell(i, j, true)
-				cgl.SetCell(i, j+1, true)
-				cgl.SetCell(i, j+2, true)
-			} else {
-				cgl.SetCell(i, j, false)
-				cgl.SetCell(i, j+1, false)
-				cgl.SetCell(i, j+2, false)
-			}
-		}
-	}
-}
-
-func (cgl *CGL) Threads() {
-	for i := 0; i < cgl.height; i++ {
-		for j := 0; j < cgl.width; j += 3 {
-			if (i+j)%2 == 0 {
-				cgl.SetCell(i, j, true)
-				cgl.SetCell(i, j+1, true)
-				cgl.SetCell(i, j+2, true)
-			} else {
-				cgl.SetCell(i, j, false)
-				cgl.SetCell(i, j+1, false)
-				cgl.SetCell(i, j+2, false)
-			}
-		}
-	}
-}
-
-func (cgl *CGL) Checkerboard() {
-	prev := true
-	for i := 0; i < cgl.height; i++ {
-		if i != 0 && i%4 == 0 {
-			prev = !prev
-		}
-		for j := 0; j < cgl.width; j += 4 {
-			if prev {
-				cgl.SetCell(i, j, true)
-				cgl.SetCell(i, j+1, true)
-				cgl.SetCell(i, j+2, true)
-				cgl.SetCell(i, j+3, true)
-				prev = false
-			} else {
-				cgl.SetCell(i, j, false)
-				cgl.SetCell(i, j+1, false)
-				cgl.SetCell(i, j+2, false)
-				cgl.SetCell(i, j+3, false)
-				prev = true
-			}
-		}
-	}
-}
-
-func (cgl *CGL) Diamonds(density int) {
-	delta := cgl.height / density
-	for h := 0; h <= cgl.height; h += delta {
-		for j := 0; j < cgl.width; j++ {
-			for i := range delta {
-				cgl.SetCell(h+i, j, true)
-				cgl.SetCell(h+i, j+1, true)
-				cgl.SetCell(h+delta-1-i, j, true)
-				cgl.SetCell(h+delta-1-i, j+1, true)
-				j++
-			}
-		}
-	}
-}
-
-func (cgl *CGL) ResetMap() {
-	for i := 0; i < cgl.height; i++ {
-		for j := 0; j < cgl.width; j++ {
-			cgl.gameMap[i][j] = false
-		}
+// gameLoop steps the universe once for every frame, it only ever steps when
+// asked so starting it doesn't skip a generation
+func (cgl *CGL) gameLoop() {
+	for done := range cgl.updateCh {
+		cgl.Rewind.Record(cgl.Universe)
+		cgl.Step()
+		close(done)
 	}
 }
 
+// Resize grows a grid engine to fit the terminal
 func (cgl *CGL) Resize(height, width int) {
-	if os.Getenv("DEFAULT") != "" {
+	if os.Getenv("DEFAULT") != "" || cgl.fixed {
 		return
 	}
-	cgl.mu.Lock()
-	defer cgl.mu.Unlock()
-	wDiff := width - cgl.width
-	hDiff := height - cgl.height
-	if wDiff > 0 {
-		for i := 0; i < cgl.height; i++ {
-			for range wDiff {
-				cgl.gameMap[i] = append(cgl.gameMap[i], false)
-			}
-		}
-		cgl.width = width
-	}
-	if hDiff > 0 {
-		for range hDiff {
-			cgl.gameMap = append(cgl.gameMap, make([]bool, cgl.width))
-		}
-		cgl.height = height
+	if g, ok := cgl.Universe.(life.Finite); ok {
+		g.Resize(height, width)
 	}
 }
 
-func (cgl *CGL) SetCell(x, y int, b bool) {
-	cgl.mu.Lock()
-	defer cgl.mu.Unlock()
-	if x < 0 || x >= cgl.height {
-		return
-	}
-	if y < 0 || y >= cgl.width {
-		return
-	}
-	cgl.gameMap[x][y] = b
+// SyncFrame lets the game loop compute the next generation, the returned
+// channel is closed once it has
+func (cgl *CGL) SyncFrame() <-chan struct{} {
+	done := make(chan struct{})
+	cgl.updateCh <- done
+	cgl.stepping = done
+	return done
 }
 
-func (cgl *CGL) GetCell(x, y int) bool {
-	cgl.mu.Lock()
-	defer cgl.mu.Unlock()
-	if x < 0 || x >= cgl.height {
-		return false
-	}
-	if y < 0 || y >= cgl.width {
-		return false
+// Wait blocks until the generation last asked for with SyncFrame is ready,
+// the universe mustn't be edited while it's being stepped
+func (cgl *CGL) Wait() {
+	if cgl.stepping != nil {
+		<-cgl.stepping
 	}
-	return cgl.gameMap[x][y]
-}
-
-func (cgl *CGL) SyncFrame() {
-	cgl.updateCh <- struct{}{}
 }
 
+// StartGame starts the game loop, it keeps running while paused
 func (cgl *CGL) StartGame() {
-	go cgl.gameLoop()
+	cgl.loop.Do(func() { go cgl.gameLoop() })
 }
 
 func getTermSize() (height, width int) {
@@ -294,10 +143,117 @@ func getTermSize() (height, width int) {
 	return H, W
 }
 
+// parseSize parses a WxH map size such as 2000x2000
+func parseSize(s string) (height, width int, err error) {
+	w, h, ok := strings.Cut(strings.ToLower(s), "x")
+	if ok {
+		width, err = strconv.Atoi(w)
+	}
+	if ok && err == nil {
+		height, err = strconv.Atoi(h)
+	}
+	if !ok || err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("invalid size %q, expected WxH such as 2000x2000", s)
+	}
+	return height, width, nil
+}
+
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		runHeadless(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		runSearch(os.Args[2:])
+		return
+	}
+	ruleFlag := flag.String("rule", life.CONWAY.String(), "Life-like rule in B/S notation, e.g. B36/S23")
+	loadFlag := flag.String("load", "", "RLE or .cells pattern `file` to place in the center of the map")
+	engineFlag := flag.String("engine", "grid", "simulation engine: grid (wraps around the edges), bitgrid (like grid, fast for huge maps of Life-like rules) or hashlife (unbounded, fast for huge patterns)")
+	topologyFlag := flag.String("topology", "", "torus (edges wrap around), bounded (dead edges) or infinite, grid defaults to torus")
+	sizeFlag := flag.String("size", "", "fixed map size `WxH` in cells, e.g. 2000x2000, defaults to the terminal size")
+	rewindFlag := flag.Int("rewind", DEFAULT_REWIND, "number of past generations kept for stepping backward, 0 disables it")
+	pauseFlag := flag.Bool("pause-when-stable", false, "pause the simulation once the map dies out or repeats an earlier generation")
+	saveFlag := flag.String("save-on-exit", "", "save the map to `file` on exit, as .cells if the name ends in .cells, RLE otherwise")
+	ageFlag := flag.Bool("age-colors", false, "color cells by how long they've been alive and leave trails where they died, Z toggles it")
+	paletteFlag := flag.String("palette", PALETTES[0].Name, "age colors: ocean, fire, mono or four hex colors `newborn,young,stable,trail`")
+	seedFlag := flag.Int64("seed", 0, "seed of the first random fill, the next ones count up from it, random unless given")
+	densityFlag := flag.Float64("density", life.DEFAULT_DENSITY, "percentage of cells a random fill brings to life")
+	soupFlag := flag.String("soup", "", "random fill a `WxH` soup centered on the screen instead of the whole map, e.g. 16x16")
+	flag.Parse()
+	soup := life.Soup{Seed: *seedFlag, Density: *densityFlag}
+	if !flagPassed("seed") {
+		soup.Seed = rand.Int63n(1_000_000_000)
+	}
+	if soup.Density < 0 || soup.Density > 100 {
+		fmt.Fprintf(os.Stderr, "CGL: invalid density %g, expected a percentage from 0 to 100\n", soup.Density)
+		os.Exit(-1)
+	}
+	if *soupFlag != "" {
+		var err error
+		soup.Height, soup.Width, err = parseSize(*soupFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "CGL: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+	palette, err := ParsePalette(*paletteFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "CGL: %v\n", err)
+		os.Exit(-1)
+	}
+	rule, err := life.ParseRule(*ruleFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "CGL: %v\n", err)
+		os.Exit(-1)
+	}
+	var pattern *life.Pattern
+	if *loadFlag != "" {
+		pattern, err = life.LoadPattern(*loadFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "CGL: Unable to load pattern: %v\n", err)
+			os.Exit(-1)
+		}
+		// The pattern's own rule applies unless one was given explicitly
+		if pattern.Rule != nil && !flagPassed("rule") {
+			rule = *pattern.Rule
+		}
+	}
 	H, W := getTermSize()
-	cgl := initCGL(H, W)
-	tui_model := InitModel(cgl, cgl.height, cgl.width)
+	mapH, mapW := H, W
+	if *sizeFlag != "" {
+		mapH, mapW, err = parseSize(*sizeFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "CGL: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+	universe, err := newUniverse(*engineFlag, *topologyFlag, mapH, mapW, rule)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "CGL: %v\n", err)
+		os.Exit(-1)
+	}
+	cgl := initCGL(universe)
+	cgl.fixed = *sizeFlag != ""
+	cgl.Rewind = NewRewind(max(*rewindFlag, 0))
+	tui_model := InitModel(cgl, H, W)
+	tui_model.PauseWhenStable = *pauseFlag
+	tui_model.AgeColors = *ageFlag
+	tui_model.Palette = palette
+	tui_model.Soup = soup
+	if pattern != nil {
+		tui_model.placePattern(pattern, false)
+	}
 	p := tea.NewProgram(
 		tui_model,
 		tea.WithMouseCellMotion(),
@@ -307,4 +263,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "CGL: Error running term app: %v", err)
 		os.Exit(-1)
 	}
+	if *saveFlag != "" {
+		if err := life.SavePattern(*saveFlag, tui_model.pattern()); err != nil {
+			fmt.Fprintf(os.Stderr, "CGL: Unable to save pattern: %v\n", err)
+			os.Exit(-1)
+		}
+	}
 }
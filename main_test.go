@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+func TestGameLoop(t *testing.T) {
+	g := life.NewGrid(8, 8, life.CONWAY)
+	g.PlacePattern(parsePattern(t, "x = 3, y = 1\n3o!"), 3, 2)
+	engine := initCGL(g)
+	// Nothing asked for yet
+	engine.Wait()
+	// Starting steps nothing on its own, each frame one generation
+	for frame := 1; frame <= 3; frame++ {
+		engine.StartGame()
+		engine.SyncFrame()
+		engine.Wait()
+		if gen := engine.Generation(); gen != frame {
+			t.Fatalf("frame %d: at generation %d", frame, gen)
+		}
+	}
+	if depth := engine.Rewind.Depth(); depth != 3 {
+		t.Errorf("%d generations recorded, want 3", depth)
+	}
+}
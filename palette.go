@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Ends of the gradient dying cells of Generations rules fade through,
+// purple to a dark orange
+const (
+	DECAY_FROM = "#ff00ff"
+	DECAY_TO   = "#872f00"
+)
+
+// Generations a dead cell leaves a trail for when coloring by age, halved
+// on 16 color terminals
+const TRAIL_LENGTH = 8
+
+// Palette colors cells by how long they've been alive, and the trails of the
+// ones that died
+type Palette struct {
+	Newborn lipgloss.TerminalColor
+	Young   lipgloss.TerminalColor
+	Stable  lipgloss.TerminalColor
+	// Hex color trails start from, they fade to black
+	Trail string
+	// Trail color for 16 color terminals, an ANSI color number
+	TrailANSI string
+}
+
+// Built in palettes, the first is the default
+var PALETTES = []struct {
+	Name string
+	Palette
+}{
+	{"ocean", Palette{
+		Newborn:   lipgloss.CompleteColor{TrueColor: "#ffffff", ANSI256: "231", ANSI: "15"},
+		Young:     lipgloss.CompleteColor{TrueColor: "#5fffd7", ANSI256: "86", ANSI: "14"},
+		Stable:    lipgloss.CompleteColor{TrueColor: "#005fff", ANSI256: "27", ANSI: "4"},
+		Trail:     "#af00ff",
+		TrailANSI: "5",
+	}},
+	{"fire", Palette{
+		Newborn:   lipgloss.CompleteColor{TrueColor: "#ffff5f", ANSI256: "227", ANSI: "11"},
+		Young:     lipgloss.CompleteColor{TrueColor: "#ff8700", ANSI256: "208", ANSI: "3"},
+		Stable:    lipgloss.CompleteColor{TrueColor: "#d70000", ANSI256: "160", ANSI: "1"},
+		Trail:     "#875f5f",
+		TrailANSI: "8",
+	}},
+	{"mono", Palette{
+		Newborn:   lipgloss.CompleteColor{TrueColor: "#ffffff", ANSI256: "231", ANSI: "15"},
+		Young:     lipgloss.CompleteColor{TrueColor: "#bcbcbc", ANSI256: "250", ANSI: "7"},
+		Stable:    lipgloss.CompleteColor{TrueColor: "#808080", ANSI256: "244", ANSI: "8"},
+		Trail:     "#585858",
+		TrailANSI: "8",
+	}},
+}
+
+// ParsePalette returns a built in palette by name, or a custom one written
+// as four hex colors for newborn, young and stable cells and trails, e.g.
+// "#ffffff,#5fffd7,#005fff,#af00ff"
+func ParsePalette(s string) (Palette, error) {
+	for _, p := range PALETTES {
+		if s == p.Name {
+			return p.Palette, nil
+		}
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		names := make([]string, len(PALETTES))
+		for i, p := range PALETTES {
+			names[i] = p.Name
+		}
+		return Palette{}, fmt.Errorf("unknown palette %q, expected %s or four hex colors separated by commas", s, strings.Join(names, ", "))
+	}
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+		if _, err := parseHex(parts[i]); err != nil {
+			return Palette{}, err
+		}
+	}
+	return Palette{
+		Newborn:   lipgloss.Color(parts[0]),
+		Young:     lipgloss.Color(parts[1]),
+		Stable:    lipgloss.Color(parts[2]),
+		Trail:     parts[3],
+		TrailANSI: "8",
+	}, nil
+}
+
+// colors returns the color of every age class, see ageTracker.classes, for
+// the color profile of the terminal. Trails need a gradient, with 16 colors
+// they're shorter and flat and without colors there are none.
+func (p Palette) colors(profile termenv.Profile) []lipgloss.TerminalColor {
+	colors := []lipgloss.TerminalColor{nil, p.Newborn, p.Young, p.Stable}
+	switch profile {
+	case termenv.Ascii:
+		return colors
+	case termenv.ANSI:
+		for range TRAIL_LENGTH / 2 {
+			colors = append(colors, lipgloss.Color(p.TrailANSI))
+		}
+		return colors
+	}
+	return append(colors, gradient(p.Trail, "#1c1c1c", TRAIL_LENGTH)...)
+}
+
+// parseHex parses a "#rrggbb" color
+func parseHex(s string) ([3]float64, error) {
+	var rgb [3]float64
+	if len(s) != 7 || s[0] != '#' {
+		return rgb, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+	}
+	for i := range rgb {
+		n, err := strconv.ParseUint(s[1+2*i:3+2*i], 16, 8)
+		if err != nil {
+			return rgb, fmt.Errorf("invalid color %q, expected #rrggbb", s)
+		}
+		rgb[i] = float64(n)
+	}
+	return rgb, nil
+}
+
+// gradient returns n colors evenly spread from one hex color to another,
+// lipgloss turns them into the closest ones the terminal has
+func gradient(from, to string, n int) []lipgloss.TerminalColor {
+	a, _ := parseHex(from)
+	b, _ := parseHex(to)
+	colors := make([]lipgloss.TerminalColor, n)
+	for s := range n {
+		t := 0.0
+		if n > 1 {
+			t = float64(s) / float64(n-1)
+		}
+		var rgb [3]int
+		for i := range rgb {
+			rgb[i] = int(a[i] + (b[i]-a[i])*t)
+		}
+		colors[s] = lipgloss.Color(fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2]))
+	}
+	return colors
+}
+
+// decayPalette returns the color of every state of a rule with the given
+// number of states, live cells keep the usual cyan
+func decayPalette(states int) []lipgloss.TerminalColor {
+	return append([]lipgloss.TerminalColor{nil, cyan}, gradient(DECAY_FROM, DECAY_TO, states-2)...)
+}
+
+// decayGlyph draws the rows x cols block of states at top, left like glyph,
+// colored by state. Half blocks show both of their cells' colors, denser
+// zoom levels the color of the liveliest cell.
+func decayGlyph(states [][]uint8, occupied [][]bool, top, left, rows, cols int, palette []lipgloss.TerminalColor) (rune, lipgloss.Style) {
+	style := lipgloss.NewStyle()
+	if rows == 2 && cols == 1 {
+		upper, lower := states[top][left], states[top+1][left]
+		switch {
+		case upper != 0 && lower != 0 && upper == lower:
+			return '█', style.Foreground(palette[upper])
+		case upper != 0 && lower != 0:
+			return '▀', style.Foreground(palette[upper]).Background(palette[lower])
+		case upper != 0:
+			return '▀', style.Foreground(palette[upper])
+		case lower != 0:
+			return '▄', style.Foreground(palette[lower])
+		}
+		return ' ', style
+	}
+	var liveliest uint8
+	for i := top; i < top+rows; i++ {
+		for j := left; j < left+cols; j++ {
+			if s := states[i][j]; s != 0 && (liveliest == 0 || s < liveliest) {
+				liveliest = s
+			}
+		}
+	}
+	if liveliest == 0 {
+		return ' ', style
+	}
+	return glyph(occupied, top, left, rows, cols), style.Foreground(palette[liveliest])
+}
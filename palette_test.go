@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestParsePalette(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Palette
+		// Part of the error, "" for none
+		err string
+	}{
+		{"fire", PALETTES[1].Palette, ""},
+		{"#ffffff, #5fffd7,#005fff ,#AF00FF", Palette{
+			Newborn:   lipgloss.Color("#ffffff"),
+			Young:     lipgloss.Color("#5fffd7"),
+			Stable:    lipgloss.Color("#005fff"),
+			Trail:     "#AF00FF",
+			TrailANSI: "8",
+		}, ""},
+		{"neon", Palette{}, `unknown palette "neon", expected ocean, fire, mono or four hex colors`},
+		{"#ffffff,#ffffff,#ffffff", Palette{}, "unknown palette"},
+		{"#fff,#ffffff,#ffffff,#ffffff", Palette{}, `invalid color "#fff"`},
+		{"#ffffff,#ffffff,#ffffff,#ffffzz", Palette{}, `invalid color "#ffffzz"`},
+		{"#ffffff,#ffffff,ffffff0,#ffffff", Palette{}, `invalid color "ffffff0"`},
+	}
+	for _, tt := range tests {
+		p, err := ParsePalette(tt.s)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.s, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: error %v, want %s", tt.s, err, tt.err)
+		case p != tt.want:
+			t.Errorf("%s: %+v, want %+v", tt.s, p, tt.want)
+		}
+	}
+}
+
+func TestPaletteColors(t *testing.T) {
+	p := PALETTES[0].Palette
+	tests := []struct {
+		profile termenv.Profile
+		// Colors after the live ones, first and last
+		trail       int
+		first, last lipgloss.TerminalColor
+	}{
+		{termenv.Ascii, 0, nil, nil},
+		{termenv.ANSI, TRAIL_LENGTH / 2, lipgloss.Color("5"), lipgloss.Color("5")},
+		{termenv.TrueColor, TRAIL_LENGTH, lipgloss.Color("#af00ff"), lipgloss.Color("#1c1c1c")},
+	}
+	for _, tt := range tests {
+		colors := p.colors(tt.profile)
+		if len(colors) != STABLE+1+tt.trail {
+			t.Fatalf("profile %d: %d colors, want %d", tt.profile, len(colors), STABLE+1+tt.trail)
+		}
+		if colors[NEWBORN] != p.Newborn || colors[YOUNG] != p.Young || colors[STABLE] != p.Stable {
+			t.Errorf("profile %d: live cells colored %v", tt.profile, colors[:STABLE+1])
+		}
+		if tt.trail > 0 && (colors[STABLE+1] != tt.first || colors[len(colors)-1] != tt.last) {
+			t.Errorf("profile %d: trail from %v to %v, want %v to %v", tt.profile, colors[STABLE+1], colors[len(colors)-1], tt.first, tt.last)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Generations kept for stepping backward unless -rewind says otherwise
+const DEFAULT_REWIND = 500
+
+// Generations between two saved frames, the ones in between are stepped
+// again from the frame before them when rewinding
+const REWIND_INTERVAL = 32
+
+// frame is one past generation, its live cells RLE compressed
+type frame struct {
+	generation int
+	top, left  int
+	rule       life.Rule
+	rle        []byte
+}
+
+func capture(u life.Universe) frame {
+	st := u.Stats()
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer can't fail
+	life.WriteRLE(&buf, u.Pattern())
+	return frame{st.Generation, st.Y, st.X, u.Rule(), buf.Bytes()}
+}
+
+func (f frame) restore(u life.Universe) {
+	p, err := life.ParseRLE(bytes.NewReader(f.rle))
+	if err != nil {
+		// Only ever parses what capture wrote
+		panic(err)
+	}
+	u.Clear()
+	u.PlacePattern(p, f.top, f.left)
+	u.SetGeneration(f.generation)
+}
+
+// span is a run of consecutive generations stepped from a saved frame
+type span struct {
+	key frame
+	// Generations that can be rewound to, from is past the key once older
+	// generations fell out of the buffer
+	from, to int
+}
+
+// Rewind holds the last generations the game loop stepped through, it's
+// safe for concurrent use
+type Rewind struct {
+	mu    sync.Mutex
+	depth int
+	spans []span
+	// Generations held by spans
+	n int
+	// Set while a past generation is on screen, the span and generation
+	// it's in and the generation the loop had stepped to
+	browsing bool
+	span     int
+	gen      int
+	present  frame
+	// The map on screen was edited, it can't be stepped to from a key
+	edited bool
+}
+
+// NewRewind returns a buffer holding up to depth generations, 0 disables it
+func NewRewind(depth int) *Rewind {
+	return &Rewind{depth: depth}
+}
+
+// truncate drops the generation on screen and the ones ahead of it
+func (r *Rewind) truncate() {
+	r.spans = r.spans[:r.span+1]
+	if s := &r.spans[r.span]; r.gen > s.from {
+		s.to = r.gen - 1
+	} else {
+		r.spans = r.spans[:r.span]
+	}
+	r.n = 0
+	for _, s := range r.spans {
+		r.n += s.to - s.from + 1
+	}
+	r.browsing = false
+	r.present = frame{}
+}
+
+// Record saves the generation on u before it's stepped, dropping any
+// generations ahead of it. Only every REWIND_INTERVAL-th generation is
+// captured, the others are stepped to again when rewinding.
+func (r *Rewind) Record(u life.Universe) {
+	if r.depth == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.browsing {
+		r.truncate()
+	}
+	gen := u.Generation()
+	if len(r.spans) > 0 && !r.edited {
+		s := &r.spans[len(r.spans)-1]
+		if gen == s.to+1 && gen-s.key.generation < REWIND_INTERVAL && u.Rule() == s.key.rule {
+			s.to = gen
+			r.n++
+			r.trim()
+			return
+		}
+	}
+	r.spans = append(r.spans, span{capture(u), gen, gen})
+	r.n++
+	r.edited = false
+	r.trim()
+}
+
+// trim forgets the oldest generations past the depth
+func (r *Rewind) trim() {
+	for r.n > r.depth {
+		s := &r.spans[0]
+		s.from++
+		r.n--
+		if s.from > s.to {
+			r.spans[0] = span{}
+			r.spans = r.spans[1:]
+		}
+	}
+}
+
+// show puts the generation r.gen of r.span on u
+func (r *Rewind) show(u life.Universe) {
+	s := r.spans[r.span]
+	s.key.restore(u)
+	r.step(u, r.gen-s.key.generation)
+}
+
+// step advances u by n generations under the rule of the span on screen
+func (r *Rewind) step(u life.Universe, n int) {
+	if rule := u.Rule(); rule != r.spans[r.span].key.rule {
+		// The universe ran under that rule before, it can't be refused
+		u.SetRule(r.spans[r.span].key.rule)
+		defer u.SetRule(rule)
+	}
+	for range n {
+		u.Step()
+	}
+}
+
+// Back restores the generation before the one on u, it's false when there
+// is none
+func (r *Rewind) Back(u life.Universe) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case !r.browsing && len(r.spans) > 0:
+		// Keep the present so Forward can come back to it
+		r.present = capture(u)
+		r.browsing = true
+		r.span = len(r.spans) - 1
+		r.gen = r.spans[r.span].to
+	case !r.browsing:
+		return false
+	case r.gen > r.spans[r.span].from:
+		r.gen--
+	case r.span > 0:
+		r.span--
+		r.gen = r.spans[r.span].to
+	default:
+		return false
+	}
+	r.show(u)
+	return true
+}
+
+// Forward restores the generation after the one on u, it's false when u
+// is already at the newest one
+func (r *Rewind) Forward(u life.Universe) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case !r.browsing:
+		return false
+	case r.gen < r.spans[r.span].to:
+		r.gen++
+		r.step(u, 1)
+	case r.span < len(r.spans)-1:
+		r.span++
+		r.gen = r.spans[r.span].from
+		r.show(u)
+	default:
+		r.present.restore(u)
+		r.present = frame{}
+		r.browsing = false
+	}
+	return true
+}
+
+// Diverge drops the generations ahead of the one on screen, which was
+// edited and no longer leads to them
+func (r *Rewind) Diverge() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.browsing {
+		r.truncate()
+	}
+	r.edited = true
+}
+
+// Depth returns how many generations back can be reached from the screen
+func (r *Rewind) Depth() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.browsing {
+		return r.n
+	}
+	depth := r.gen - r.spans[r.span].from
+	for _, s := range r.spans[:r.span] {
+		depth += s.to - s.from + 1
+	}
+	return depth
+}
+
+// rewind steps the paused map one recorded generation back or forward
+func (m *Model) rewind(forward bool) {
+	m.GameEngine.Wait()
+	r := m.GameEngine.Rewind
+	if m.History.Edited() {
+		// Whatever came after the edited generation is gone
+		r.Diverge()
+	}
+	var ok bool
+	if forward {
+		ok = r.Forward(m.GameEngine.Universe)
+	} else {
+		ok = r.Back(m.GameEngine.Universe)
+	}
+	switch {
+	case ok:
+		m.History.Reset()
+		m.sample()
+		m.Status = fmt.Sprintf("Generation %d, %d more recorded before it", m.GameEngine.Generation(), r.Depth())
+	case forward:
+		m.Status = "This is the newest generation, ENTER to run on from it"
+	default:
+		m.Status = "No older generations recorded"
+	}
+}
+
+// carryOn forgets the edits to the map on screen before running on from it,
+// the generations recorded before can't be stepped to from an edited map
+func (m *Model) carryOn() {
+	if m.History.Edited() {
+		m.GameEngine.Rewind.Diverge()
+	}
+	m.History.Reset()
+}
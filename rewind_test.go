@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// rewindRun steps a universe the way the game loop does and keeps the hash
+// of every generation it went through
+type rewindRun struct {
+	t *testing.T
+	u life.Universe
+	r *Rewind
+	// Hash of each generation, by generation
+	hashes map[int]uint64
+}
+
+func newRewindRun(t *testing.T, depth int) *rewindRun {
+	t.Helper()
+	u, err := life.NewSparse(life.CONWAY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := life.ParseRLE(strings.NewReader("x = 3, y = 3\nb2o$2o$bo!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.PlacePattern(p, 0, 0)
+	run := &rewindRun{t, u, NewRewind(depth), map[int]uint64{}}
+	run.hashes[0] = u.Hash()
+	return run
+}
+
+// step records and steps n generations
+func (run *rewindRun) step(n int) {
+	for range n {
+		run.r.Record(run.u)
+		run.u.Step()
+		run.hashes[run.u.Generation()] = run.u.Hash()
+	}
+}
+
+// at fails unless the universe is at generation gen as it was stepped
+func (run *rewindRun) at(gen int) {
+	run.t.Helper()
+	if got := run.u.Generation(); got != gen {
+		run.t.Fatalf("at generation %d, want %d", got, gen)
+	}
+	if run.u.Hash() != run.hashes[gen] {
+		run.t.Fatalf("generation %d has other cells than when it was stepped", gen)
+	}
+}
+
+// back steps back through generations from, from-1, .. to and fails if
+// it can go further
+func (run *rewindRun) back(from, to int) {
+	run.t.Helper()
+	for gen := from; gen >= to; gen-- {
+		if !run.r.Back(run.u) {
+			run.t.Fatalf("can't step back to generation %d", gen)
+		}
+		run.at(gen)
+		if depth := run.r.Depth(); depth != gen-to {
+			run.t.Fatalf("generation %d has %d generations before it, want %d", gen, depth, gen-to)
+		}
+	}
+	if run.r.Back(run.u) {
+		run.t.Fatalf("stepped back past generation %d to %d", to, run.u.Generation())
+	}
+	run.at(to)
+}
+
+// forward steps forward through generations from .. to and fails if it
+// can go further
+func (run *rewindRun) forward(from, to int) {
+	run.t.Helper()
+	for gen := from; gen <= to; gen++ {
+		if !run.r.Forward(run.u) {
+			run.t.Fatalf("can't step forward to generation %d", gen)
+		}
+		run.at(gen)
+	}
+	if run.r.Forward(run.u) {
+		run.t.Fatalf("stepped forward past generation %d to %d", to, run.u.Generation())
+	}
+	run.at(to)
+}
+
+func TestRewind(t *testing.T) {
+	tests := []struct {
+		name     string
+		depth    int
+		stepped  int
+		earliest int
+	}{
+		{"depth 1", 1, 10, 9},
+		{"not full", 500, 10, 0},
+		{"full", 5, 20, 15},
+		{"full across saved frames", 40, 100, 60},
+		{"oldest saved frame dropped", 2 * REWIND_INTERVAL, 5 * REWIND_INTERVAL, 3 * REWIND_INTERVAL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := newRewindRun(t, tt.depth)
+			run.step(tt.stepped)
+			if depth := run.r.Depth(); depth != tt.stepped-tt.earliest {
+				t.Fatalf("depth %d, want %d", depth, tt.stepped-tt.earliest)
+			}
+			run.back(tt.stepped-1, tt.earliest)
+			run.forward(tt.earliest+1, tt.stepped)
+			// And back again from the present
+			run.back(tt.stepped-1, tt.earliest)
+		})
+	}
+}
+
+func TestRewindDisabled(t *testing.T) {
+	run := newRewindRun(t, 0)
+	run.step(10)
+	if run.r.Back(run.u) || run.r.Forward(run.u) || run.r.Depth() != 0 {
+		t.Fatal("rewound with a depth of 0")
+	}
+	run.at(10)
+}
+
+func TestRewindRunOn(t *testing.T) {
+	// Running on from a past generation drops the ones ahead of it
+	run := newRewindRun(t, 100)
+	run.step(50)
+	run.r.Back(run.u)
+	run.r.Back(run.u)
+	run.r.Back(run.u)
+	run.at(47)
+	run.step(5)
+	run.at(52)
+	run.back(51, 0)
+	run.forward(1, 52)
+}
+
+func TestRewindDiverge(t *testing.T) {
+	run := newRewindRun(t, 100)
+	run.step(40)
+	for range 6 {
+		run.r.Back(run.u)
+	}
+	run.at(34)
+	// An edit makes the generation on screen the newest one
+	run.u.SetCell(-20, -20, true)
+	run.u.SetCell(-20, -19, true)
+	run.u.SetCell(-20, -18, true)
+	run.hashes[34] = run.u.Hash()
+	run.r.Diverge()
+	if run.r.Forward(run.u) {
+		t.Fatal("stepped forward from an edited generation")
+	}
+	run.back(33, 0)
+	run.forward(1, 34)
+	// Generations run on from the edit are stepped from it, not from the
+	// frame saved before it
+	run.step(10)
+	run.back(43, 0)
+	run.forward(1, 44)
+}
+
+func TestRewindRuleChange(t *testing.T) {
+	run := newRewindRun(t, 100)
+	run.step(10)
+	if err := run.u.SetRule(life.MustParseRule("B36/S23")); err != nil {
+		t.Fatal(err)
+	}
+	run.step(10)
+	run.back(19, 0)
+	run.forward(1, 20)
+	if rule := run.u.Rule().String(); rule != "B36/S23" {
+		t.Errorf("rewinding left the rule at %s", rule)
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Soups each worker searches between saves of the search state
+const SEARCH_BATCH = 64
+
+// censusEntry tallies one kind of object
+type censusEntry struct {
+	Count int    `json:"count"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name,omitempty"`
+	// Lowest seed of a soup it came out of
+	Seed int64 `json:"seed"`
+}
+
+// searchState is what `cgl search` saves after every batch of soups, the
+// soups searched are seeds FirstSeed up to FirstSeed+Soups
+type searchState struct {
+	Rule        string                  `json:"rule"`
+	Density     float64                 `json:"density"`
+	Width       int                     `json:"width"`
+	Height      int                     `json:"height"`
+	Limit       int                     `json:"limit"`
+	FirstSeed   int64                   `json:"first_seed"`
+	Soups       int                     `json:"soups"`
+	Objects     int                     `json:"objects"`
+	Unstable    []int64                 `json:"unstable"`
+	Census      map[string]*censusEntry `json:"census"`
+	Generations int64                   `json:"generations"`
+}
+
+// soupResult is what came out of one soup
+type soupResult struct {
+	done        bool
+	stable      bool
+	generations int
+	objects     []life.Object
+}
+
+// searchSoup runs the soup with the given seed until it stabilizes and
+// classifies the objects left
+func (s *searchState) searchSoup(seed int64, rule life.Rule) soupResult {
+	u, err := life.NewSparse(rule)
+	if err != nil {
+		// Checked before the search starts
+		panic(err)
+	}
+	soup := life.Soup{Seed: seed, Density: s.Density, Height: s.Height, Width: s.Width}
+	soup.Fill(u, s.Height, s.Width)
+	period, ok := life.Stabilize(u, s.Limit)
+	res := soupResult{done: true, stable: ok, generations: u.Generation()}
+	if !ok {
+		return res
+	}
+	for _, cells := range life.Separate(u, period) {
+		res.objects = append(res.objects, life.Classify(cells, rule))
+	}
+	return res
+}
+
+// add tallies the result of the soup with the given seed
+func (s *searchState) add(seed int64, res soupResult) {
+	s.Soups++
+	s.Generations += int64(res.generations)
+	if !res.stable {
+		s.Unstable = append(s.Unstable, seed)
+		return
+	}
+	for _, o := range res.objects {
+		s.Objects++
+		e, ok := s.Census[o.Code]
+		if !ok {
+			e = &censusEntry{Kind: o.Kind.String(), Name: o.Name, Seed: seed}
+			s.Census[o.Code] = e
+		}
+		e.Count++
+		e.Seed = min(e.Seed, seed)
+	}
+}
+
+// save writes the state to path, through a temporary file so an interrupted
+// save doesn't lose the last one
+func (s *searchState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadSearch reads the state saved at path, ok is false if there's none
+func loadSearch(path string) (s *searchState, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	s = &searchState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, false, fmt.Errorf("%s: %v", path, err)
+	}
+	if s.Census == nil {
+		s.Census = make(map[string]*censusEntry)
+	}
+	return s, true, nil
+}
+
+// report writes a summary of the search, objects grouped by kind and the
+// most common first
+func (s *searchState) report(w io.Writer) {
+	fmt.Fprintf(w, "Searched %d soups of %dx%d at %g%% density under %s, seeds %d to %d\n", s.Soups, s.Width, s.Height, s.Density, s.Rule, s.FirstSeed, s.FirstSeed+int64(s.Soups)-1)
+	fmt.Fprintf(w, "%d objects in %d generations\n", s.Objects, s.Generations)
+	if len(s.Unstable) > 0 {
+		fmt.Fprintf(w, "%d soups didn't stabilize within %d generations, seeds %v\n", len(s.Unstable), s.Limit, s.Unstable)
+	}
+	codes := make([]string, 0, len(s.Census))
+	for code := range s.Census {
+		codes = append(codes, code)
+	}
+	slices.SortFunc(codes, func(a, b string) int {
+		return cmp.Or(cmp.Compare(s.Census[b].Count, s.Census[a].Count), cmp.Compare(a, b))
+	})
+	for _, kind := range []life.Kind{life.StillLife, life.Oscillator, life.Spaceship, life.Unknown} {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		header := false
+		for _, code := range codes {
+			e := s.Census[code]
+			if e.Kind != kind.String() {
+				continue
+			}
+			if !header {
+				fmt.Fprintf(w, "\n%s:\n", kind)
+				fmt.Fprintln(tw, "  count\tshare\tapgcode\tname\tfirst seed")
+				header = true
+			}
+			fmt.Fprintf(tw, "  %d\t%.3f%%\t%s\t%s\t%d\n", e.Count, 100*float64(e.Count)/float64(s.Objects), code, e.Name, e.Seed)
+		}
+		tw.Flush()
+	}
+}
+
+// runSearch implements `cgl search`: run random soups until they stabilize
+// and tally the objects they leave behind, on every core. The state is saved
+// after every batch of soups and a search picks up where the last one with
+// the same state file stopped.
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	soups := fs.Int("soups", 1000, "number of soups to search")
+	statePath := fs.String("state", "search.json", "`file` the tallies are saved to after every batch of soups, an existing one is resumed")
+	seed := fs.Int64("seed", 0, "seed of the first soup of a new search, the next ones count up from it")
+	ruleFlag := fs.String("rule", life.CONWAY.String(), "Life-like rule in B/S notation")
+	density := fs.Float64("density", 50, "percentage of live cells in a soup")
+	size := fs.String("size", "16x16", "soup size `WxH`")
+	limit := fs.Int("limit", 20000, "generations a soup gets to stabilize")
+	workers := fs.Int("workers", runtime.NumCPU(), "soups searched in parallel")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s search [options]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	rule, err := life.ParseRule(*ruleFlag)
+	if err != nil {
+		headlessFail("%v", err)
+	}
+	if rule.States > 2 {
+		headlessFail("soup search only runs Life-like rules")
+	}
+	if rule.Birth[0] {
+		headlessFail("%v", life.ErrBirthOnZero)
+	}
+	height, width, err := parseSize(*size)
+	if err != nil {
+		headlessFail("%v", err)
+	}
+	if *density < 0 || *density > 100 {
+		headlessFail("invalid density %g, expected a percentage from 0 to 100", *density)
+	}
+	s := &searchState{
+		Rule:      rule.String(),
+		Density:   *density,
+		Width:     width,
+		Height:    height,
+		Limit:     *limit,
+		FirstSeed: *seed,
+		Census:    make(map[string]*censusEntry),
+	}
+	if saved, ok, err := loadSearch(*statePath); err != nil {
+		headlessFail("Unable to load the search state: %v", err)
+	} else if ok {
+		seedPassed := false
+		fs.Visit(func(f *flag.Flag) {
+			seedPassed = seedPassed || f.Name == "seed"
+		})
+		if err := s.resumable(*statePath, saved, seedPassed); err != nil {
+			headlessFail("%v", err)
+		}
+		s = saved
+		fmt.Fprintf(os.Stderr, "CGL: Resuming after %d soups\n", s.Soups)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	err = s.search(ctx, rule, *soups, *workers, func(searched int) error {
+		if err := s.save(*statePath); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "CGL: %d soups, %d objects, %.0f soups/s\n", s.Soups, s.Objects, float64(searched)/time.Since(start).Seconds())
+		return nil
+	})
+	if err != nil {
+		headlessFail("Unable to save the search state: %v", err)
+	}
+	s.report(os.Stdout)
+}
+
+// resumable checks that the search saved at path can go on with the options
+// of s, seedPassed tells whether --seed was given
+func (s *searchState) resumable(path string, saved *searchState, seedPassed bool) error {
+	if saved.Rule != s.Rule || saved.Density != s.Density || saved.Width != s.Width || saved.Height != s.Height || saved.Limit != s.Limit {
+		return fmt.Errorf("%s holds a search of %dx%d soups at %g%% density under %s with a limit of %d generations, pass the same options or another --state", path, saved.Width, saved.Height, saved.Density, saved.Rule, saved.Limit)
+	}
+	if seedPassed && saved.FirstSeed != s.FirstSeed {
+		return fmt.Errorf("%s holds a search starting at seed %d, pass --seed %d, none or another --state", path, saved.FirstSeed, saved.FirstSeed)
+	}
+	return nil
+}
+
+// search tallies the next soups soups on workers goroutines, calling done
+// with the number searched so far after every batch of them. It stops early
+// once ctx is done or done returns an error.
+func (s *searchState) search(ctx context.Context, rule life.Rule, soups, workers int, done func(searched int) error) error {
+	searched := 0
+	batch := max(workers, 1) * SEARCH_BATCH
+	for searched < soups && ctx.Err() == nil {
+		results := make([]soupResult, min(batch, soups-searched))
+		next := s.FirstSeed + int64(s.Soups)
+		seeds := make(chan int, len(results))
+		for i := range results {
+			seeds <- i
+		}
+		close(seeds)
+		var wg sync.WaitGroup
+		for range max(workers, 1) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range seeds {
+					if ctx.Err() != nil {
+						return
+					}
+					results[i] = s.searchSoup(next+int64(i), rule)
+				}
+			}()
+		}
+		wg.Wait()
+		// Only soups up to the first one cut short by an interrupt count,
+		// the rest are searched again on resume
+		for i, res := range results {
+			if !res.done {
+				break
+			}
+			s.add(next+int64(i), res)
+			searched++
+		}
+		if err := done(searched); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// newSearch returns a search of small soups, quick to stabilize
+func newSearch() *searchState {
+	return &searchState{
+		Rule:    life.CONWAY.String(),
+		Density: 50,
+		Width:   8,
+		Height:  8,
+		Limit:   500,
+		Census:  make(map[string]*censusEntry),
+	}
+}
+
+func TestSearchResume(t *testing.T) {
+	const soups = 80
+	whole := newSearch()
+	if err := whole.search(context.Background(), life.CONWAY, soups, 4, func(int) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if whole.Soups != soups || whole.Objects == 0 || len(whole.Census) == 0 {
+		t.Fatalf("searched %d soups for %d objects of %d kinds", whole.Soups, whole.Objects, len(whole.Census))
+	}
+
+	// Interrupted after the first batch, then resumed from the saved state
+	path := filepath.Join(t.TempDir(), "search.json")
+	if _, ok, err := loadSearch(path); ok || err != nil {
+		t.Fatalf("loaded a search that was never saved: %v, %v", ok, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := newSearch()
+	err := first.search(ctx, life.CONWAY, soups, 1, func(int) error {
+		cancel()
+		return first.save(path)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Soups != SEARCH_BATCH {
+		t.Fatalf("interrupted after %d soups, want %d", first.Soups, SEARCH_BATCH)
+	}
+	saved, ok, err := loadSearch(path)
+	if !ok || err != nil {
+		t.Fatalf("loading the search: %v, %v", ok, err)
+	}
+	if err := newSearch().resumable(path, saved, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.search(context.Background(), life.CONWAY, soups-saved.Soups, 2, func(int) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, whole) {
+		t.Errorf("resumed search\n%+v\nwant\n%+v", saved, whole)
+	}
+}
+
+func TestSearchResumable(t *testing.T) {
+	saved := newSearch()
+	saved.FirstSeed = 100
+	tests := []struct {
+		name       string
+		change     func(s *searchState)
+		seedPassed bool
+		// Part of the error, "" for none
+		err string
+	}{
+		{"same options", func(s *searchState) { s.FirstSeed = 100 }, true, ""},
+		{"seed not passed", func(s *searchState) {}, false, ""},
+		{"other seed", func(s *searchState) {}, true, "starting at seed 100, pass --seed 100"},
+		{"other rule", func(s *searchState) { s.Rule = "B36/S23" }, false, "under B3/S23"},
+		{"other density", func(s *searchState) { s.Density = 40 }, false, "at 50% density"},
+		{"other size", func(s *searchState) { s.Width = 16 }, false, "of 8x8 soups"},
+		{"other limit", func(s *searchState) { s.Limit = 100 }, false, "limit of 500 generations"},
+	}
+	for _, tt := range tests {
+		s := newSearch()
+		tt.change(s)
+		err := s.resumable("search.json", saved, tt.seedPassed)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: error %v, want %s", tt.name, err, tt.err)
+		}
+	}
+}
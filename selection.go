@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Background of the selected part of the map
+var selected = lipgloss.Color("237")
+
+// cursorBlock returns the board cells under the mouse, the whole terminal
+// cell at the current zoom level
+func (m *Model) cursorBlock() image.Rectangle {
+	z := ZOOMS[m.Zoom]
+	p := image.Pt(m.viewLeft+m.cursorX, m.viewTop+m.cursorY)
+	return image.Rectangle{p, p.Add(image.Pt(z.Cols, z.Rows))}
+}
+
+// selectMouse drags out a selection box with the left button, or moves the
+// selection when the drag starts inside it. The right button deselects.
+func (m *Model) selectMouse(msg tea.MouseMsg) {
+	switch msg.Action {
+	case tea.MouseActionPress:
+		switch msg.Button {
+		case tea.MouseButtonLeft:
+			block := m.cursorBlock()
+			if block.Overlaps(m.Selection) {
+				// Lifted off the map until the button is released
+				m.History.Begin()
+				m.moving = m.selectionPattern()
+				m.fillSelection(func(bool) bool { return false })
+				m.moveGrab = block.Min.Sub(m.Selection.Min)
+				m.EditState = Moving
+				break
+			}
+			m.selectFrom = block
+			m.Selection = block
+			m.EditState = Selecting
+		case tea.MouseButtonRight:
+			m.Selection = image.Rectangle{}
+		}
+	case tea.MouseActionMotion:
+		if m.EditState == Selecting {
+			m.Selection = m.selectFrom.Union(m.cursorBlock())
+		}
+	case tea.MouseActionRelease:
+		if m.EditState == Moving {
+			row, col := m.moveAt()
+			m.edits().PlacePattern(m.moving, row, col)
+			m.Selection = m.Selection.Sub(m.Selection.Min).Add(image.Pt(col, row))
+			m.moving = nil
+			m.History.End()
+		}
+		m.EditState = Observing
+	}
+}
+
+// moveAt returns where the top left corner of a selection being moved goes
+func (m *Model) moveAt() (row, col int) {
+	at := m.cursorBlock().Min.Sub(m.moveGrab)
+	return at.Y, at.X
+}
+
+// selectionPattern returns the cells in the selection, the pattern is the
+// size of the box even if its edges are dead
+func (m *Model) selectionPattern() *life.Pattern {
+	r := m.Selection
+	p := life.NewPattern(r.Dy(), r.Dx())
+	for i, row := range m.GameEngine.Region(r.Min.Y, r.Min.X, r.Dy(), r.Dx()) {
+		for j, alive := range row {
+			if alive {
+				p.Cells = append(p.Cells, life.Point{Row: i, Col: j})
+			}
+		}
+	}
+	return p
+}
+
+// fillSelection sets every cell in the selection to f of its state
+func (m *Model) fillSelection(f func(alive bool) bool) {
+	r := m.Selection
+	u := m.edits()
+	for i, row := range m.GameEngine.Region(r.Min.Y, r.Min.X, r.Dy(), r.Dx()) {
+		for j, alive := range row {
+			if f(alive) != alive {
+				u.SetCell(r.Min.Y+i, r.Min.X+j, !alive)
+			}
+		}
+	}
+}
+
+// selectionKey runs the selection command for a key, false if the key isn't
+// one of them
+func (m *Model) selectionKey(key string) bool {
+	if m.Selection.Empty() {
+		return false
+	}
+	m.History.Begin()
+	defer m.History.End()
+	switch strings.ToLower(key) {
+	case "y":
+		m.copySelection()
+	case "x":
+		m.copySelection()
+		m.fillSelection(func(bool) bool { return false })
+	case "a":
+		m.fillSelection(func(bool) bool { return true })
+	case "d":
+		m.fillSelection(func(bool) bool { return false })
+	case "i":
+		m.fillSelection(func(alive bool) bool { return !alive })
+	case "e", "q", "w":
+		p := m.selectionPattern()
+		m.fillSelection(func(bool) bool { return false })
+		switch strings.ToLower(key) {
+		case "e":
+			p = p.Rotate()
+		case "q":
+			p = p.RotateCounter()
+		case "w":
+			p = p.Mirror()
+		}
+		// Turned around the middle of the box
+		c := m.Selection.Min.Add(m.Selection.Size().Div(2))
+		at := c.Sub(image.Pt(p.Width/2, p.Height/2))
+		m.edits().PlacePattern(p, at.Y, at.X)
+		m.Selection = image.Rect(at.X, at.Y, at.X+p.Width, at.Y+p.Height)
+	default:
+		return false
+	}
+	// Like vim, a command ends the visual block but keeps it selected
+	m.visual = false
+	return true
+}
+
+// copySelection puts the selection on the clipboard, the system one too as
+// RLE so it can be pasted elsewhere
+func (m *Model) copySelection() {
+	p := m.selectionPattern()
+	var buf bytes.Buffer
+	life.WriteRLE(&buf, p)
+	p.Name = "Clipboard"
+	m.Clipboard = p
+	if err := clipboard.WriteAll(buf.String()); err != nil {
+		// No clipboard tool installed or no display, ask the terminal
+		osc52.New(buf.String()).WriteTo(os.Stderr)
+	}
+	m.Status = fmt.Sprintf("Copied %dx%d, %d cells", p.Width, p.Height, p.Population())
+}
+
+// paste picks up the system clipboard as the stamp, or what was last copied
+// here if it doesn't hold a pattern
+func (m *Model) paste() tea.Cmd {
+	if text, err := clipboard.ReadAll(); err == nil && strings.TrimSpace(text) != "" {
+		if cmd, ok := m.pasteText(text); ok {
+			return cmd
+		}
+	}
+	if m.Clipboard == nil {
+		m.Status = "Nothing to paste, the clipboard holds no pattern"
+		return nil
+	}
+	m.Status = ""
+	return m.pickStamp(m.Clipboard)
+}
+
+// pasteText picks up RLE or Plaintext as the stamp, e.g. pasted into the
+// terminal
+func (m *Model) pasteText(text string) (tea.Cmd, bool) {
+	// Terminals send the lines of a paste ending in carriage returns
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	p, err := life.ReadPattern(strings.NewReader(text))
+	if err != nil || p.Width == 0 || p.Height == 0 {
+		m.Status = "Pasted text isn't an RLE or Plaintext pattern"
+		if err != nil {
+			m.Status += fmt.Sprintf(": %v", err)
+		}
+		return nil, false
+	}
+	if p.Name == "" {
+		p.Name = "Clipboard"
+	}
+	m.Status = ""
+	return m.pickStamp(p), true
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// picture draws the top left of u, o for live cells and . for the rest
+func picture(u life.Universe, height, width int) string {
+	var b strings.Builder
+	for _, row := range u.Region(0, 0, height, width) {
+		for _, alive := range row {
+			if alive {
+				b.WriteByte('o')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parsePattern reads an RLE pattern, failing the test if it's invalid
+func parsePattern(t *testing.T, rle string) *life.Pattern {
+	t.Helper()
+	p, err := life.ParseRLE(strings.NewReader(rle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// testModel returns a model editing u, with nothing on the clipboard
+func testModel(u life.Universe) *Model {
+	return &Model{GameEngine: initCGL(u)}
+}
+
+func TestSelectionKey(t *testing.T) {
+	const start = "" +
+		".......\n" +
+		"..ooo..\n" +
+		"..o....\n" +
+		".......\n"
+	tests := []struct {
+		key  string
+		want string
+		// Selection afterwards, and cells on the clipboard
+		selection image.Rectangle
+		copied    int
+	}{
+		{"y", start, image.Rect(2, 1, 5, 3), 4},
+		{"x", "" +
+			".......\n" +
+			".......\n" +
+			".......\n" +
+			".......\n", image.Rect(2, 1, 5, 3), 4},
+		{"a", "" +
+			".......\n" +
+			"..ooo..\n" +
+			"..ooo..\n" +
+			".......\n", image.Rect(2, 1, 5, 3), 0},
+		{"i", "" +
+			".......\n" +
+			".......\n" +
+			"...oo..\n" +
+			".......\n", image.Rect(2, 1, 5, 3), 0},
+		// Turned around the middle of the box
+		{"e", "" +
+			".......\n" +
+			"..oo...\n" +
+			"...o...\n" +
+			"...o...\n", image.Rect(2, 1, 4, 4), 0},
+		{"Q", "" +
+			".......\n" +
+			"..o....\n" +
+			"..o....\n" +
+			"..oo...\n", image.Rect(2, 1, 4, 4), 0},
+		{"w", "" +
+			".......\n" +
+			"..ooo..\n" +
+			"....o..\n" +
+			".......\n", image.Rect(2, 1, 5, 3), 0},
+	}
+	for _, tt := range tests {
+		g := life.NewGrid(4, 7, life.CONWAY)
+		g.PlacePattern(parsePattern(t, "x = 3, y = 2\n3o$o!"), 1, 2)
+		m := testModel(g)
+		m.Selection = image.Rect(2, 1, 5, 3)
+		m.visual = true
+		if !m.selectionKey(tt.key) {
+			t.Fatalf("%s: not a selection command", tt.key)
+		}
+		if got := picture(g, 4, 7); got != tt.want {
+			t.Errorf("%s: map is\n%swant\n%s", tt.key, got, tt.want)
+		}
+		if m.Selection != tt.selection {
+			t.Errorf("%s: selected %v, want %v", tt.key, m.Selection, tt.selection)
+		}
+		if m.visual {
+			t.Errorf("%s: still in a visual block", tt.key)
+		}
+		switch {
+		case tt.copied == 0 && m.Clipboard != nil:
+			t.Errorf("%s: copied %d cells", tt.key, m.Clipboard.Population())
+		case tt.copied != 0 && (m.Clipboard == nil || m.Clipboard.Population() != tt.copied || m.Clipboard.Width != 3 || m.Clipboard.Height != 2):
+			t.Errorf("%s: copied %+v, want the 3x2 box with %d cells", tt.key, m.Clipboard, tt.copied)
+		}
+		// One undo takes the whole command back
+		m.History.Undo(g)
+		if got := picture(g, 4, 7); got != start {
+			t.Errorf("%s: undone to\n%swant\n%s", tt.key, got, start)
+		}
+	}
+}
+
+func TestSelectionKeyIgnored(t *testing.T) {
+	m := testModel(life.NewGrid(4, 7, life.CONWAY))
+	if m.selectionKey("y") {
+		t.Error("y: a command with nothing selected")
+	}
+	m.Selection = image.Rect(2, 1, 5, 3)
+	if m.selectionKey("z") {
+		t.Error("z: not a selection command")
+	}
+	if m.History.Edited() {
+		t.Error("an edit was recorded")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"image"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+var (
+	// How the stamp or a shape looks before it's placed
+	ghost = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	// How cells about to be erased look
+	erasing = lipgloss.NewStyle().Foreground(lipgloss.Color("88"))
+)
+
+// pickStamp makes p follow the mouse until it's put away, nil puts the
+// current one away
+func (m *Model) pickStamp(p *life.Pattern) tea.Cmd {
+	m.Stamp = p
+	return m.mouseMode()
+}
+
+// mouseMode returns the mouse tracking the editor needs, the ghost of a
+// stamp follows the mouse even with no button held
+func (m *Model) mouseMode() tea.Cmd {
+	if m.Stamp != nil {
+		return tea.EnableMouseAllMotion
+	}
+	return tea.EnableMouseCellMotion
+}
+
+// stampAt returns where the top left corner of the stamp goes, centered on
+// the mouse or the keyboard cursor
+func (m *Model) stampAt() (row, col int) {
+	row, col = m.cursorY, m.cursorX
+	if m.KeyCursor {
+		row, col = m.cursor.Y-m.viewTop, m.cursor.X-m.viewLeft
+	}
+	return row - m.Stamp.Height/2, col - m.Stamp.Width/2
+}
+
+// stamp places the stamp centered on the mouse as one edit, or puts it away
+// on a right click
+func (m *Model) stamp(button tea.MouseButton) tea.Cmd {
+	switch button {
+	case tea.MouseButtonLeft:
+		row, col := m.stampAt()
+		m.History.Begin()
+		m.view().PlacePattern(m.Stamp, row, col)
+		m.History.End()
+	case tea.MouseButtonRight:
+		return m.pickStamp(nil)
+	}
+	return nil
+}
+
+// turnStamp rotates or mirrors the stamp for one of its keys
+func (m *Model) turnStamp(key string) {
+	switch key {
+	case "e", "E":
+		m.Stamp = m.Stamp.Rotate()
+	case "q", "Q":
+		m.Stamp = m.Stamp.RotateCounter()
+	case "w", "W":
+		m.Stamp = m.Stamp.Mirror()
+	}
+}
+
+// pendingCells marks the cells the stamp, a selection being moved or a
+// shape being dragged out would change in the rows x cols block of the
+// screen, and whether they'd come to life. cells is nil when there's nothing
+// pending.
+func (m *Model) pendingCells(rows, cols int) (cells [][]bool, alive bool) {
+	if !m.hasCursor && !m.KeyCursor || m.GameState != Mapping {
+		return nil, false
+	}
+	var points []image.Point
+	alive = true
+	switch {
+	case m.moving != nil:
+		row, col := m.moveAt()
+		points = patternPoints(m.moving, row-m.viewTop, col-m.viewLeft)
+	case m.Stamp != nil:
+		row, col := m.stampAt()
+		points = patternPoints(m.Stamp, row, col)
+	case m.drawingShape():
+		for _, p := range m.shapeCells() {
+			points = append(points, p.Sub(image.Pt(m.viewLeft, m.viewTop)))
+		}
+		alive = m.EditState == Adding
+	default:
+		return nil, false
+	}
+	cells = make([][]bool, rows)
+	for i := range cells {
+		cells[i] = make([]bool, cols)
+	}
+	for _, p := range points {
+		if p.Y >= 0 && p.Y < rows && p.X >= 0 && p.X < cols {
+			cells[p.Y][p.X] = true
+		}
+	}
+	return cells, alive
+}
+
+// patternPoints returns the live cells of p placed at row, col
+func patternPoints(p *life.Pattern, row, col int) []image.Point {
+	points := make([]image.Point, len(p.Cells))
+	for i, c := range p.Cells {
+		points[i] = image.Pt(col+c.Col, row+c.Row)
+	}
+	return points
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Names of the editor tools, by tool
+var TOOLS = []string{"draw", "line", "rectangle", "filled rectangle", "ellipse", "filled ellipse", "flood fill", "select"}
+
+// setTool switches to the tool of a number key
+func (m *Model) setTool(key string) {
+	tool := int(key[0] - '1')
+	if tool < 0 || tool >= len(TOOLS) {
+		return
+	}
+	m.Tool = tool
+	m.EditState = Observing
+}
+
+// drawingShape reports whether a shape is being dragged out
+func (m *Model) drawingShape() bool {
+	return m.Tool >= LineTool && m.Tool <= FilledEllipseTool && (m.EditState == Adding || m.EditState == Removing)
+}
+
+// toolMouse handles the mouse for the shape and flood fill tools. Shapes are
+// dragged out from where the button went down and drawn once it's released,
+// live with the left button and dead with the right.
+func (m *Model) toolMouse(msg tea.MouseMsg) {
+	at := m.cursorBlock().Min
+	switch msg.Action {
+	case tea.MouseActionPress:
+		var editState int
+		switch msg.Button {
+		case tea.MouseButtonLeft:
+			editState = Adding
+		case tea.MouseButtonRight:
+			editState = Removing
+		default:
+			return
+		}
+		if m.Tool == FloodTool {
+			m.History.Begin()
+			m.floodFill(at, editState == Adding)
+			m.History.End()
+			return
+		}
+		m.shapeFrom, m.shapeTo = at, at
+		m.EditState = editState
+	case tea.MouseActionMotion:
+		if m.drawingShape() {
+			m.shapeTo = at
+		}
+	case tea.MouseActionRelease:
+		if m.drawingShape() {
+			m.History.Begin()
+			u := m.edits()
+			for _, p := range m.shapeCells() {
+				u.SetCell(p.Y, p.X, m.EditState == Adding)
+			}
+			m.History.End()
+		}
+		m.EditState = Observing
+	}
+}
+
+// shapeCells returns the board cells of the shape being dragged out
+func (m *Model) shapeCells() []image.Point {
+	var cells []image.Point
+	plot := func(row, col int) {
+		cells = append(cells, image.Pt(col, row))
+	}
+	from, to := m.shapeFrom, m.shapeTo
+	// Corners included
+	box := image.Rectangle{from, to}.Canon()
+	box.Max = box.Max.Add(image.Pt(1, 1))
+	switch m.Tool {
+	case LineTool:
+		bresenham(from.Y, from.X, to.Y, to.X, plot)
+	case RectTool, FilledRectTool:
+		for y := box.Min.Y; y < box.Max.Y; y++ {
+			for x := box.Min.X; x < box.Max.X; x++ {
+				edge := y == box.Min.Y || y == box.Max.Y-1 || x == box.Min.X || x == box.Max.X-1
+				if edge || m.Tool == FilledRectTool {
+					plot(y, x)
+				}
+			}
+		}
+	case EllipseTool, FilledEllipseTool:
+		// Inscribed in the box, the outline is the inside cells next to an
+		// outside one so it has no gaps
+		cy, cx := float64(box.Min.Y+box.Max.Y-1)/2, float64(box.Min.X+box.Max.X-1)/2
+		ry, rx := float64(box.Dy())/2, float64(box.Dx())/2
+		inside := func(y, x int) bool {
+			dy, dx := (float64(y)-cy)/ry, (float64(x)-cx)/rx
+			return dy*dy+dx*dx <= 1
+		}
+		for y := box.Min.Y; y < box.Max.Y; y++ {
+			for x := box.Min.X; x < box.Max.X; x++ {
+				if !inside(y, x) {
+					continue
+				}
+				edge := !inside(y-1, x) || !inside(y+1, x) || !inside(y, x-1) || !inside(y, x+1)
+				if edge || m.Tool == FilledEllipseTool {
+					plot(y, x)
+				}
+			}
+		}
+	}
+	return cells
+}
+
+// floodFill brings the dead cells connected to at to life, or kills the live
+// ones when alive is false. It spreads no further than the screen, an
+// infinite plane would never end.
+func (m *Model) floodFill(at image.Point, alive bool) {
+	rows, cols := m.viewSize()
+	cells := m.GameEngine.Region(m.viewTop, m.viewLeft, rows, cols)
+	start := at.Sub(image.Pt(m.viewLeft, m.viewTop))
+	if !start.In(image.Rect(0, 0, cols, rows)) || cells[start.Y][start.X] == alive {
+		return
+	}
+	u := m.view()
+	filled := 0
+	queue := []image.Point{start}
+	cells[start.Y][start.X] = alive
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		u.SetCell(p.Y, p.X, alive)
+		filled++
+		for _, d := range []image.Point{{0, -1}, {0, 1}, {-1, 0}, {1, 0}} {
+			n := p.Add(d)
+			if n.In(image.Rect(0, 0, cols, rows)) && cells[n.Y][n.X] != alive {
+				cells[n.Y][n.X] = alive
+				queue = append(queue, n)
+			}
+		}
+	}
+	m.Status = fmt.Sprintf("Filled %d cells", filled)
+	if !alive {
+		m.Status = fmt.Sprintf("Cleared %d cells", filled)
+	}
+}
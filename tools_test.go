@@ -0,0 +1,131 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+func TestShapeCellsEllipse(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     int
+		from, to image.Point
+		want     string
+	}{
+		{"wide", EllipseTool, image.Pt(0, 0), image.Pt(8, 4), "" +
+			"..ooooo...\n" +
+			"oo.....oo.\n" +
+			"o.......o.\n" +
+			"oo.....oo.\n" +
+			"..ooooo...\n" +
+			"..........\n"},
+		// Dragged up and to the left
+		{"filled", FilledEllipseTool, image.Pt(8, 4), image.Pt(0, 0), "" +
+			"..ooooo...\n" +
+			"ooooooooo.\n" +
+			"ooooooooo.\n" +
+			"ooooooooo.\n" +
+			"..ooooo...\n" +
+			"..........\n"},
+		{"even sides", EllipseTool, image.Pt(0, 0), image.Pt(7, 5), "" +
+			"..oooo....\n" +
+			".o....o...\n" +
+			"o......o..\n" +
+			"o......o..\n" +
+			".o....o...\n" +
+			"..oooo....\n"},
+		{"one cell", EllipseTool, image.Pt(1, 1), image.Pt(1, 1), "" +
+			"..........\n" +
+			".o........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n"},
+		{"flat", EllipseTool, image.Pt(0, 1), image.Pt(6, 1), "" +
+			"..........\n" +
+			"ooooooo...\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n"},
+	}
+	for _, tt := range tests {
+		g := life.NewGrid(6, 10, life.CONWAY)
+		m := testModel(g)
+		m.Tool, m.shapeFrom, m.shapeTo = tt.tool, tt.from, tt.to
+		cells := m.shapeCells()
+		for _, p := range cells {
+			g.SetCell(p.Y, p.X, true)
+		}
+		if got := picture(g, 6, 10); got != tt.want {
+			t.Errorf("%s: drew\n%swant\n%s", tt.name, got, tt.want)
+		}
+		if n := g.Stats().Population; n != len(cells) {
+			t.Errorf("%s: %d cells for %d on the map", tt.name, len(cells), n)
+		}
+	}
+}
+
+func TestFloodFill(t *testing.T) {
+	const wall = "" +
+		"..........\n" +
+		".oooo.....\n" +
+		".o..o.....\n" +
+		".oooo.....\n" +
+		"..........\n" +
+		"..........\n" +
+		"..........\n"
+	tests := []struct {
+		name  string
+		at    image.Point
+		alive bool
+		want  string
+		// Cells changed, 0 when nothing was
+		filled int
+	}{
+		{"inside", image.Pt(2, 2), true, "" +
+			"..........\n" +
+			".oooo.....\n" +
+			".oooo.....\n" +
+			".oooo.....\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n", 2},
+		// No further than the 8x6 screen
+		{"outside", image.Pt(0, 0), true, "" +
+			"oooooooo..\n" +
+			"oooooooo..\n" +
+			"oo..oooo..\n" +
+			"oooooooo..\n" +
+			"oooooooo..\n" +
+			"oooooooo..\n" +
+			"..........\n", 36},
+		{"clearing", image.Pt(4, 3), false, "" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n" +
+			"..........\n", 10},
+		{"already alive", image.Pt(1, 1), true, wall, 0},
+		{"off the screen", image.Pt(0, 6), true, wall, 0},
+	}
+	for _, tt := range tests {
+		g := life.NewGrid(7, 10, life.CONWAY)
+		g.PlacePattern(parsePattern(t, "x = 4, y = 3\n4o$o2bo$4o!"), 1, 1)
+		m := testModel(g)
+		m.Height, m.Width = 3, 8
+		m.History.Begin()
+		m.floodFill(tt.at, tt.alive)
+		m.History.End()
+		if got := picture(g, 7, 10); got != tt.want {
+			t.Errorf("%s: filled\n%swant\n%s", tt.name, got, tt.want)
+		}
+		if n := m.History.Undo(g); n != tt.filled {
+			t.Errorf("%s: changed %d cells, want %d", tt.name, n, tt.filled)
+		}
+	}
+}
@@ -5,23 +5,30 @@ import (
 	"image"
 	"io"
 	"math"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
-	ncanvas "github.com/NimbleMarkets/ntcharts/canvas"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/Cybergenik/cgl/life"
 )
 
 const (
-	HEADING_SIZE = 10
+	HEADING_SIZE = 11
 	TITLE        = `  _____                                _____                      ___  __   _ ___   
  / ___/__  ___ _    _____ ___ _____   / ___/__ ___ _  ___   ___  / _/ / /  (_) _/__ 
 / /__/ _ \/ _ \ |/|/ / _  / // (_-<  / (_ / _ /  ' \/ -_)  / _ \/ _/ / /__/ / _/ -_)
 \___/\___/_//_/__,__/\_,_/\_, /___/  \___/\_,_/_/_/_/\__/  \___/_/  /____/_/_/ \__/ 
                          /___/                                                     
 `
+	// First terminal row of the canvas
+	CANVAS_TOP = HEADING_SIZE - 1
 )
 
 // Style
@@ -47,10 +54,29 @@ const (
 	Mapping        = 0
 	Playing        = 1
 	PresetChoosing = 2
+	RuleChoosing   = 3
+	FileLoading    = 4
+	FileSaving     = 5
+	Skipping       = 6
+	Paused         = 7
+	Seeking        = 8
+	Browsing       = 9
 	// Edit State
 	Observing = 0
 	Removing  = 1
 	Adding    = 2
+	Panning   = 3
+	Selecting = 4
+	Moving    = 5
+	// Editor tools, in the order of their number keys
+	DrawTool          = 0
+	LineTool          = 1
+	RectTool          = 2
+	FilledRectTool    = 3
+	EllipseTool       = 4
+	FilledEllipseTool = 5
+	FloodTool         = 6
+	SelectTool        = 7
 	// Preset choices
 	RAND     = "Random Fill"
 	EDGES    = "Edge tracing"
@@ -72,13 +98,16 @@ func (d itemDelegate) Height() int                             { return 1 }
 func (d itemDelegate) Spacing() int                            { return 0 }
 func (d itemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
 func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	i, ok := listItem.(item)
-	if !ok {
+	var str string
+	switch i := listItem.(type) {
+	case item:
+		str = fmt.Sprintf("%d. %s", index+1, i)
+	case patternItem:
+		str = fmt.Sprintf("%d. %s", index+1, i.Name)
+	default:
 		return
 	}
 
-	str := fmt.Sprintf("%d. %s", index+1, i)
-
 	fn := itemStyle.Render
 	if index == m.Index() {
 		fn = func(s ...string) string {
@@ -93,8 +122,84 @@ type Model struct {
 	GameEngine *CGL
 	FPS        time.Duration
 	PresetList list.Model
+	RuleList   list.Model
+	// Pattern library, stamped with the mouse once picked
+	LibraryList list.Model
+	Stamp       *life.Pattern
+	// What the left mouse button does in the editor
+	Tool int
+	// Board cells selected with SelectTool, empty when there's no selection
+	Selection image.Rectangle
+	// Last pattern copied, for when the system clipboard can't be read
+	Clipboard *life.Pattern
+	// Block the selection box was dragged from
+	selectFrom image.Rectangle
+	// Edit with the keyboard cursor, at cursor in board coordinates, with a
+	// pending vim style count and a visual block selection from visualFrom
+	KeyCursor  bool
+	cursor     image.Point
+	count      int
+	visual     bool
+	visualFrom image.Point
+	// Ends of the line, or corners of the box, of a shape being dragged out
+	shapeFrom image.Point
+	shapeTo   image.Point
+	// Selection lifted off the map while it's dragged, and where it was
+	// grabbed relative to its top left corner
+	moving   *life.Pattern
+	moveGrab image.Point
+	Input    textinput.Model
+	// Place loaded patterns at the last mouse position instead of centered
+	AtCursor bool
+	// Seed, density and size of the next random fill, the seed goes up by
+	// one after each
+	Soup life.Soup
+	// Random fill on the map, noted in saved files
+	filled *life.Soup
+	Status string
+	// Undo and redo of edits made since the map last stepped
+	History History
+	// Index into ZOOMS
+	Zoom        int
+	ShowMinimap bool
+	ShowChart   bool
+	// CensusHidden, CensusPanel or CensusLabels
+	Census int
+	census *census
+	// Set while a census is taken in the background
+	counting bool
+	// Color cells by how long they've been alive, with fading trails
+	AgeColors bool
+	Palette   Palette
+	ages      ageTracker
+	// Stats of the generations shown, for the chart
+	Chart statsLog
+	// Watches the generations shown for a repeat
+	Detector *life.Detector
+	// Pause the simulation once it dies out or repeats
+	PauseWhenStable bool
+	cycle           *life.Cycle
+	// Board coordinates of the top left corner of the screen
+	viewTop  int
+	viewLeft int
+	// Last terminal cell of a middle button drag
+	panY int
+	panX int
+	// Set while a skip ahead runs in the background, the engine is left
+	// alone and the map drawn before it is shown
+	busy bool
+	skip *skip
+	// Last screen drawn from the engine
+	screen screen
+	// Generation to pause at, 0 runs on
+	untilGen int
+	// State to go back to when the run until prompt is cancelled
+	seekFrom   int
 	mousePrevY int
 	mousePrevX int
+	cursorY    int
+	cursorX    int
+	hasCursor  bool
 	GameState  int
 	EditState  int
 	Height     int
@@ -119,100 +224,340 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds := []tea.Cmd{}
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.busy {
+			if msg.Type == tea.KeyCtrlC {
+				m.skip.stop.Store(true)
+				return m, tea.Quit
+			}
+			break
+		}
+		m.Status = ""
+		switch m.GameState {
+		case FileLoading, FileSaving, Skipping, Seeking:
+			return m, m.updateInput(msg)
+		case Browsing:
+			return m, m.updateLibrary(msg)
+		case Mapping:
+			if msg.Paste {
+				cmd, _ := m.pasteText(string(msg.Runes))
+				return m, cmd
+			}
+			if msg.Type == tea.KeyTab {
+				m.toggleKeyCursor()
+				return m, nil
+			}
+			if m.KeyCursor && m.cursorKey(msg) {
+				return m, nil
+			}
+		}
 		switch msg.Type {
 		case tea.KeyCtrlC, tea.KeyEsc:
 			switch m.GameState {
-			case Playing:
+			case Playing, Paused:
 				return m, tea.Quit
 			case Mapping:
+				if m.Stamp != nil && msg.Type == tea.KeyEsc {
+					cmds = append(cmds, m.pickStamp(nil))
+					break
+				}
+				if !m.Selection.Empty() && msg.Type == tea.KeyEsc {
+					m.Selection = image.Rectangle{}
+					break
+				}
 				return m, tea.Quit
-			case PresetChoosing:
+			case PresetChoosing, RuleChoosing:
 				m.GameState = Mapping
 			}
 		case tea.KeyEnter:
 			if m.GameState == Playing {
 				break
+			} else if m.GameState == Paused {
+				m.GameState = Playing
+				cmds = append(cmds, frameTick(m.FPS))
 			} else if m.GameState == Mapping {
 				m.GameState = Playing
+				m.carryOn()
+				m.sample()
 				m.GameEngine.StartGame()
 				cmds = append(cmds, tea.DisableMouse, tea.ClearScreen, frameTick(m.FPS))
 			} else if m.GameState == PresetChoosing {
 				choice, ok := m.PresetList.SelectedItem().(item)
+				// A finite map is filled whole, an infinite one or a centered
+				// soup where it's on screen, and a selection on its own
+				var view life.Universe = m.view()
+				height, width, finite := m.gridSize()
+				if !m.Selection.Empty() {
+					view = viewport{m.edits(), m.Selection.Min.Y, m.Selection.Min.X}
+					height, width = m.Selection.Dy(), m.Selection.Dx()
+				} else if finite && (choice != RAND || m.Soup.Height == 0) {
+					view = m.edits()
+				} else {
+					height, width = m.viewSize()
+				}
+				m.History.Begin()
 				if ok {
+					// Every other fill replaces the soup
+					m.filled = nil
 					switch choice {
 					case RAND:
-						m.GameEngine.RandomFill()
+						soup := m.Soup
+						soup.Fill(view, height, width)
+						m.filled = &soup
+						m.Soup.Seed++
+						m.Status = fmt.Sprintf("Random fill, %s", soup)
 					case EDGES:
-						m.GameEngine.EdgeFill()
+						life.EdgeFill(view, height, width)
 					case PILLARS:
-						m.GameEngine.PillarFill()
+						life.PillarFill(view, height, width)
 					case ROWS:
-						m.GameEngine.RowFill()
+						life.RowFill(view, height, width)
 					case DOTTED:
-						m.GameEngine.DottedLines()
+						life.DottedLines(view, height, width)
 					case THREADS:
-						m.GameEngine.Threads()
+						life.Threads(view, height, width)
 					case CHECKERS:
-						m.GameEngine.Checkerboard()
+						life.Checkerboard(view, height, width)
 					case DIAMONDS:
-						m.GameEngine.Diamonds(5)
+						life.Diamonds(view, height, width, 5)
 					}
 				}
+				m.History.End()
+				m.GameState = Mapping
+			} else if m.GameState == RuleChoosing {
+				if err := m.GameEngine.SetRule(life.RULES[m.RuleList.Index()].Rule); err != nil {
+					m.Status = err.Error()
+				}
 				m.GameState = Mapping
 			}
+		case tea.KeyRunes:
+			switch string(msg.Runes) {
+			case "e", "E", "q", "Q", "w", "W":
+				if m.GameState == Mapping && m.Stamp != nil {
+					m.turnStamp(string(msg.Runes))
+				} else if m.GameState == Mapping {
+					m.selectionKey(string(msg.Runes))
+				}
+			case "y", "Y", "x", "X", "a", "A", "d", "D", "i", "I":
+				if m.GameState == Mapping {
+					m.selectionKey(string(msg.Runes))
+				}
+			case "1", "2", "3", "4", "5", "6", "7", "8":
+				if m.GameState == Mapping {
+					m.setTool(string(msg.Runes))
+				}
+			case "v", "V":
+				if m.GameState == Mapping {
+					if m.Tool == SelectTool {
+						m.Tool = DrawTool
+					} else {
+						m.Tool = SelectTool
+					}
+				}
+			case "r", "R":
+				if m.GameState == Mapping {
+					m.GameState = RuleChoosing
+				}
+			case "b", "B":
+				if m.GameState == Mapping {
+					m.openLibrary()
+				}
+			case "o", "O":
+				if m.GameState == Mapping {
+					m.GameState = FileLoading
+					m.Input.Reset()
+					m.Input.Placeholder = "pattern.rle"
+					cmds = append(cmds, m.Input.Focus())
+				}
+			case "s", "S":
+				if m.GameState == Mapping {
+					m.GameState = FileSaving
+					m.Input.Reset()
+					m.Input.Placeholder = "pattern.rle or pattern.cells"
+					cmds = append(cmds, m.Input.Focus())
+				}
+			case "c", "C":
+				if m.GameState == Mapping || m.GameState == Playing || m.GameState == Paused {
+					m.centerView()
+				}
+			case "p", "P":
+				switch m.GameState {
+				case Mapping:
+					cmds = append(cmds, m.paste())
+				case Playing:
+					m.GameState = Paused
+					m.untilGen = 0
+				case Paused:
+					m.GameState = Playing
+					cmds = append(cmds, frameTick(m.FPS))
+				}
+			case "n", "N":
+				if m.GameState == Paused {
+					done := m.GameEngine.SyncFrame()
+					cmds = append(cmds, func() tea.Msg {
+						<-done
+						return FrameMsg{}
+					})
+				}
+			case "g", "G":
+				if m.GameState == Mapping || m.GameState == Paused {
+					m.seekFrom = m.GameState
+					m.GameState = Seeking
+					m.Input.Reset()
+					m.Input.Placeholder = strconv.Itoa(m.GameEngine.Generation() + 100)
+					cmds = append(cmds, m.Input.Focus())
+				}
+			case "f", "F":
+				if m.GameState == Mapping {
+					m.GameState = Skipping
+					m.Input.Reset()
+					m.Input.Placeholder = "1000000"
+					cmds = append(cmds, m.Input.Focus())
+				}
+			case "h", "j", "k", "l":
+				m.panKey(string(msg.Runes))
+			case "[":
+				m.setZoom(m.Zoom - 1)
+			case "]":
+				m.setZoom(m.Zoom + 1)
+			case "m", "M":
+				m.ShowMinimap = !m.ShowMinimap
+			case "u", "U":
+				cmds = append(cmds, m.toggleCensus())
+			case "z", "Z":
+				m.AgeColors = !m.AgeColors
+				m.ages.Reset()
+				if m.AgeColors {
+					m.ages.Observe(m.GameEngine, m.viewRect())
+				}
+			case "t", "T":
+				m.ShowChart = !m.ShowChart
+				m.sample()
+				m.clampView()
+			case ",", "<":
+				if m.GameState == Mapping || m.GameState == Paused {
+					m.rewind(false)
+				}
+			case ".", ">":
+				if m.GameState == Mapping || m.GameState == Paused {
+					m.rewind(true)
+				}
+			case "+", "=":
+				m.FPS++
+				m.FPS = min(m.FPS, 200)
+			case "-":
+				m.FPS--
+				m.FPS = max(m.FPS, 1)
+			}
 		case tea.KeySpace:
-			if m.GameState == Playing {
+			if m.GameState == Playing || m.GameState == Paused {
+				m.GameEngine.Wait()
 				m.GameState = Mapping
-				cmds = append(cmds, tea.EnableMouseCellMotion)
+				m.untilGen = 0
+				cmds = append(cmds, m.mouseMode())
 			} else if m.GameState == Mapping {
 				m.GameState = PresetChoosing
-			} else if m.GameState == PresetChoosing {
+			} else if m.GameState == PresetChoosing || m.GameState == RuleChoosing {
 				break
 			}
 		case tea.KeyBackspace:
 			switch m.GameState {
-			case Playing:
-				m.GameEngine.ResetMap()
+			case Playing, Paused:
+				m.GameEngine.Wait()
+				m.clear()
 				m.GameState = Mapping
-				cmds = append(cmds, tea.EnableMouseCellMotion)
+				m.untilGen = 0
+				cmds = append(cmds, m.mouseMode())
 			case Mapping:
-				m.GameEngine.ResetMap()
-			case PresetChoosing:
+				m.clear()
+			case PresetChoosing, RuleChoosing:
 				m.GameState = Mapping
 			}
-		case tea.KeyRight:
-			m.FPS++
-			m.FPS = min(m.FPS, 200)
+		case tea.KeyCtrlZ:
+			if m.GameState == Mapping {
+				if n := m.History.Undo(m.GameEngine); n > 0 {
+					m.Status = fmt.Sprintf("Undid %d cell changes", n)
+				} else {
+					m.Status = "Nothing to undo"
+				}
+			}
+		case tea.KeyCtrlY:
+			if m.GameState == Mapping {
+				if n := m.History.Redo(m.GameEngine); n > 0 {
+					m.Status = fmt.Sprintf("Redid %d cell changes", n)
+				} else {
+					m.Status = "Nothing to redo"
+				}
+			}
 		case tea.KeyLeft:
-			m.FPS--
-			m.FPS = max(m.FPS, 1)
+			m.panKey("h")
+		case tea.KeyDown:
+			m.panKey("j")
+		case tea.KeyUp:
+			m.panKey("k")
+		case tea.KeyRight:
+			m.panKey("l")
 		}
 	case tea.MouseMsg:
-		if m.GameState != Mapping {
+		if m.GameState != Mapping || m.busy {
+			break
+		}
+		z := ZOOMS[m.Zoom]
+		m.cursorX, m.cursorY = msg.X*z.Cols, (msg.Y-CANVAS_TOP)*z.Rows
+		m.hasCursor = true
+		if msg.Button == tea.MouseButtonMiddle {
+			switch msg.Action {
+			case tea.MouseActionPress:
+				m.EditState = Panning
+			case tea.MouseActionMotion:
+				if m.EditState == Panning {
+					m.pan(m.panY-msg.Y, m.panX-msg.X)
+				}
+			case tea.MouseActionRelease:
+				m.EditState = Observing
+			}
+			m.panY, m.panX = msg.Y, msg.X
+			break
+		}
+		if m.Stamp != nil && msg.Action == tea.MouseActionPress {
+			cmds = append(cmds, m.stamp(msg.Button))
+			break
+		}
+		if m.Tool == SelectTool || m.EditState == Moving {
+			m.selectMouse(msg)
+			break
+		}
+		if m.Zoom != 0 && msg.Action == tea.MouseActionPress {
+			m.Status = "Zoom in to draw ([)"
+			break
+		}
+		if m.Tool != DrawTool {
+			m.toolMouse(msg)
 			break
 		}
 		switch msg.Action {
 		case tea.MouseActionPress:
 			switch msg.Button {
 			case tea.MouseButton(tea.MouseButtonLeft):
+				m.History.Begin()
 				m.EditState = Adding
 				m.mousePrevX = msg.X
-				gameY := ((msg.Y - 9) * 2)
+				gameY := ((msg.Y - CANVAS_TOP) * 2)
 				m.mousePrevY = gameY
 				m.updateGameState(msg.X, gameY, true)
 			case tea.MouseButton(tea.MouseButtonRight):
+				m.History.Begin()
 				m.EditState = Removing
 			}
 		case tea.MouseActionMotion:
 			switch msg.Button {
 			case tea.MouseButton(tea.MouseButtonLeft):
 				if m.EditState == Adding {
-					gameY := ((msg.Y - 9) * 2)
+					gameY := ((msg.Y - CANVAS_TOP) * 2)
 					m.updateGameState(msg.X, gameY, true)
 				}
 			case tea.MouseButton(tea.MouseButtonRight):
 				if m.EditState == Removing {
-					gameY := ((msg.Y - 9) * 2)
+					gameY := ((msg.Y - CANVAS_TOP) * 2)
 					m.updateGameState(msg.X, gameY, false)
 				}
 			}
@@ -223,35 +568,284 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case tea.MouseButton(tea.MouseButtonRight):
 				m.EditState = Observing
 			}
+			m.History.End()
 		}
 	case tea.WindowSizeMsg:
 		m.Height = msg.Height - HEADING_SIZE
 		m.Width = msg.Width
 		m.PresetList.SetWidth(m.Width)
+		m.RuleList.SetWidth(m.Width)
+		m.LibraryList.SetWidth(m.Width)
 		m.GameEngine.Resize(m.Height*2, m.Width)
+		m.clampView()
+	case CensusMsg:
+		m.counting = false
+		if m.Census != CensusHidden {
+			m.census = msg.census
+		}
+	case SkipTickMsg:
+		if m.busy {
+			return m, skipTick()
+		}
+		return m, nil
+	case SkipDoneMsg:
+		m.busy = false
+		m.skip = nil
+		m.sample()
+		m.Status = fmt.Sprintf("Skipped %d generations, now at generation %d", msg.Generations, m.GameEngine.Generation())
 	case TickMsg:
 		if m.GameState == Playing {
+			if m.untilGen > 0 && m.GameEngine.Generation() >= m.untilGen {
+				m.GameState = Paused
+				m.Status = fmt.Sprintf("Reached generation %d", m.untilGen)
+				m.untilGen = 0
+				return m, nil
+			}
+			m.sample()
+			if m.GameState != Playing {
+				// Stabilized and PauseWhenStable is on
+				return m, nil
+			}
 			//sync frame render to game state
 			m.GameEngine.SyncFrame()
 			return m, frameTick(m.FPS)
 		}
 		return m, nil
+	case FrameMsg:
+		m.sample()
+		return m, nil
 	}
 	if m.GameState == PresetChoosing {
 		var cmd tea.Cmd
 		m.PresetList, cmd = m.PresetList.Update(msg)
 		cmds = append(cmds, cmd)
+	} else if m.GameState == RuleChoosing {
+		var cmd tea.Cmd
+		m.RuleList, cmd = m.RuleList.Update(msg)
+		cmds = append(cmds, cmd)
+	} else if m.GameState == Browsing {
+		// Filtering results come back as messages
+		var cmd tea.Cmd
+		m.LibraryList, cmd = m.LibraryList.Update(msg)
+		cmds = append(cmds, cmd)
 	}
 	return m, tea.Batch(cmds...)
 }
 
-// Uses Bresenhams line algorithm to fill: https://en.wikipedia.org/wiki/Bresenham's_line_algorithm
+// sample records the stats of the generation on screen for the chart and
+// checks whether it repeats an earlier one
+func (m *Model) sample() {
+	m.Chart.Record(m.GameEngine.Stats())
+	if m.AgeColors {
+		m.ages.Observe(m.GameEngine, m.viewRect())
+	}
+	c, ok := m.Detector.Observe(m.GameEngine)
+	if !ok {
+		m.cycle = nil
+		return
+	}
+	if m.cycle == nil && m.PauseWhenStable && m.GameState == Playing {
+		m.GameState = Paused
+		m.untilGen = 0
+		m.Status = fmt.Sprintf("Paused, %s", c)
+	}
+	m.cycle = &c
+}
+
+// FrameMsg redraws the map once a single step is done
+type FrameMsg struct{}
+
+type SkipDoneMsg struct {
+	Generations int
+}
+
+// SkipTickMsg redraws the progress of a skip ahead
+type SkipTickMsg struct{}
+
+// Longest a skip ahead holds the engine at once
+const SKIP_CHUNK_TIME = 50 * time.Millisecond
+
+// skip is a skip ahead running in the background
+type skip struct {
+	generations int
+	// Generations stepped so far
+	done atomic.Int64
+	// Set to give up after the chunk being stepped
+	stop atomic.Bool
+}
+
+// skipAhead steps the engine s.generations off the UI goroutine, in chunks
+// that grow while they take less than SKIP_CHUNK_TIME so the engine is free
+// to be read between them
+func skipAhead(engine *CGL, s *skip) tea.Cmd {
+	return func() tea.Msg {
+		engine.Rewind.Record(engine.Universe)
+		for chunk, left := 1, s.generations; left > 0 && !s.stop.Load(); {
+			n := min(chunk, left)
+			start := time.Now()
+			engine.StepN(n)
+			left -= n
+			s.done.Add(int64(n))
+			if took := time.Since(start); took < SKIP_CHUNK_TIME {
+				chunk *= 2
+			} else if took > 2*SKIP_CHUNK_TIME {
+				chunk = max(chunk/2, 1)
+			}
+		}
+		return SkipDoneMsg{int(s.done.Load())}
+	}
+}
+
+func skipTick() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return SkipTickMsg{}
+	})
+}
+
+// seek runs the simulation until the generation typed in the prompt
+func (m *Model) seek(input string) tea.Cmd {
+	n, err := strconv.Atoi(input)
+	if err != nil || n <= 0 {
+		m.Status = fmt.Sprintf("Can't run until generation %q, expected a positive number", input)
+		return nil
+	}
+	if gen := m.GameEngine.Generation(); n <= gen {
+		m.Status = fmt.Sprintf("Already at generation %d", gen)
+		return nil
+	}
+	m.untilGen = n
+	cmds := []tea.Cmd{frameTick(m.FPS)}
+	if m.GameState == Mapping {
+		m.carryOn()
+		m.sample()
+		m.GameEngine.StartGame()
+		cmds = append(cmds, tea.DisableMouse, tea.ClearScreen)
+	}
+	m.GameState = Playing
+	return tea.Batch(cmds...)
+}
+
+func (m *Model) updateInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		if m.GameState == Seeking {
+			m.GameState = m.seekFrom
+		} else {
+			m.GameState = Mapping
+		}
+		m.Input.Blur()
+		return nil
+	case tea.KeyTab:
+		if m.GameState == FileLoading {
+			m.AtCursor = !m.AtCursor
+		}
+		return nil
+	case tea.KeyEnter:
+		action := m.GameState
+		m.GameState = Mapping
+		if action == Seeking {
+			m.GameState = m.seekFrom
+		}
+		m.Input.Blur()
+		path := strings.TrimSpace(m.Input.Value())
+		if path == "" {
+			return nil
+		}
+		if action == Seeking {
+			return m.seek(path)
+		}
+		if action == Skipping {
+			n, err := strconv.Atoi(path)
+			if err != nil || n <= 0 {
+				m.Status = fmt.Sprintf("Can't skip %q generations, expected a positive number", path)
+				return nil
+			}
+			m.busy = true
+			m.skip = &skip{generations: n}
+			m.carryOn()
+			return tea.Batch(skipAhead(m.GameEngine, m.skip), skipTick())
+		}
+		if action == FileSaving && strings.HasSuffix(strings.ToLower(path), ".csv") {
+			if err := m.Chart.SaveCSV(path); err != nil {
+				m.Status = fmt.Sprintf("Export failed: %v", err)
+			} else {
+				m.Status = fmt.Sprintf("Exported stats of %d generations to %s", len(m.Chart.stats), path)
+			}
+			return nil
+		}
+		if action == FileSaving {
+			p := m.pattern()
+			if err := life.SavePattern(path, p); err != nil {
+				m.Status = fmt.Sprintf("Save failed: %v", err)
+			} else {
+				m.Status = fmt.Sprintf("Saved %d cells to %s", p.Population(), path)
+			}
+			return nil
+		}
+		p, err := life.LoadPattern(path)
+		if err != nil {
+			m.Status = fmt.Sprintf("Load failed: %v", err)
+			return nil
+		}
+		m.History.Begin()
+		m.placePattern(p, m.AtCursor)
+		m.History.End()
+		if p.Rule != nil {
+			if err := m.GameEngine.SetRule(*p.Rule); err != nil {
+				m.Status = fmt.Sprintf("Loaded %s but kept rule %s: %v", p.Name, m.GameEngine.Rule(), err)
+			}
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.Input, cmd = m.Input.Update(msg)
+	return cmd
+}
+
+// pattern returns the map to save, noting the random fill it started from
+func (m *Model) pattern() *life.Pattern {
+	p := m.GameEngine.Pattern()
+	if m.filled != nil {
+		p.Comments = append(p.Comments, fmt.Sprintf("Random fill: %s", m.filled))
+	}
+	return p
+}
+
+// placePattern stamps p centered on the screen, or with its top left corner
+// at the mouse cursor
+func (m *Model) placePattern(p *life.Pattern, atCursor bool) {
+	rows, cols := m.viewSize()
+	x, y := (rows-p.Height)/2, (cols-p.Width)/2
+	if atCursor && m.hasCursor {
+		x, y = m.cursorY, m.cursorX
+	}
+	m.view().PlacePattern(p, x, y)
+	// The map is the pattern now, not the soup
+	m.filled = nil
+	name := p.Name
+	if name == "" {
+		name = "pattern"
+	}
+	m.Status = fmt.Sprintf("Loaded %s (%dx%d)", name, p.Width, p.Height)
+	if height, width, ok := m.gridSize(); ok && (p.Height > height || p.Width > width) {
+		m.Status += ", clipped to the map"
+	}
+}
+
 func (m *Model) updateGameState(x, y int, b bool) {
 	x0, y0 := m.mousePrevX, m.mousePrevY
 	m.mousePrevX, m.mousePrevY = x, y
-	if m.GameEngine.GetCell(y, x) {
+	view := m.view()
+	if view.Cell(y, x) {
 		y0++
 	}
+	bresenham(y0, x0, y, x, func(row, col int) {
+		view.SetCell(row, col, b)
+	})
+}
+
+// Uses Bresenhams line algorithm to fill: https://en.wikipedia.org/wiki/Bresenham's_line_algorithm
+func bresenham(y0, x0, y, x int, plot func(row, col int)) {
 	deltaX := math.Abs(float64(x - x0))
 	deltaY := math.Abs(float64(y - y0))
 	signX := 1
@@ -264,7 +858,7 @@ func (m *Model) updateGameState(x, y int, b bool) {
 	}
 	err := deltaX - deltaY
 	for {
-		m.GameEngine.SetCell(y0, x0, b)
+		plot(y0, x0)
 		if x == x0 && y == y0 {
 			break
 		}
@@ -279,68 +873,165 @@ func (m *Model) updateGameState(x, y int, b bool) {
 		}
 	}
 }
+
 func (m *Model) View() string {
-	canvas := ncanvas.New(m.Width, m.Height)
-	canvas.Fill(ncanvas.NewCell(' '))
-	for h := 0; h < m.Height*2; h++ {
-		for w := 0; w < m.Width; w++ {
-			if m.GameEngine.GetCell(h, w) {
-				if h%2 == 0 {
-					p := image.Point{w, h / 2}
-					c := canvas.Cell(p)
-					if c.Rune == '▄' {
-						canvas.SetRuneWithStyle(p, '█', colors[0])
-					} else {
-						canvas.SetRuneWithStyle(p, '▀', colors[0])
-					}
-				} else {
-					p := image.Point{w, (h - 1) / 2}
-					c := canvas.Cell(p)
-					if c.Rune == '▀' {
-						canvas.SetRuneWithStyle(p, '█', colors[0])
-					} else {
-						canvas.SetRuneWithStyle(p, '▄', colors[0])
-					}
-				}
-			} else {
-				canvas.SetRune(image.Point{w, h}, ' ')
-			}
-		}
-	}
 	var titleMsg string
 	switch m.GameState {
 	case Playing:
 		titleMsg = TITLE
 	case Mapping:
-		titleMsg = `MAP EDITOR
-LMB draw/RMB erase
-SPACE: choose fill preset
-BACKSPACE: reset
-ENTER: draw life!
-        `
+		if m.KeyCursor {
+			var countMsg string
+			if m.count > 0 {
+				countMsg = fmt.Sprintf("  COUNT: %d", m.count)
+			}
+			titleMsg = fmt.Sprintf(`KEYBOARD CURSOR at %d,%d%s
+HJKL/←↓↑→: move, with counts like 5j
+SPACE: toggle the cell, or place the stamp
+V: visual block, then Y/X/A/D/I/E/Q/W
+P: paste  B: library  ESC: end visual/leave
+TAB: back to the mouse  ENTER: draw life!`, m.cursor.X, m.cursor.Y, countMsg)
+			break
+		}
+		if m.Stamp != nil {
+			titleMsg = fmt.Sprintf(`STAMP: %s (%dx%d)
+LMB: stamp  RMB/ESC: put away
+E/Q: rotate clockwise/counterclockwise
+W: mirror left to right
+HJKL/MMB: pan  [/]: zoom
+CTRL-Z/Y: undo/redo  ENTER: draw life!`, m.Stamp.Name, m.Stamp.Width, m.Stamp.Height)
+			break
+		}
+		if m.Tool == SelectTool {
+			titleMsg = `SELECT (V: back to drawing)
+LMB drag: select, or move the selection
+Y/X/P: copy/cut/paste  RMB/ESC: deselect
+A/D/I: fill/clear/invert
+E/Q/W: rotate/mirror in place
+HJKL/MMB: pan  [/]: zoom  CTRL-Z/Y: undo/redo`
+			break
+		}
+		titleMsg = fmt.Sprintf("MAP EDITOR  TOOL: %s (1-8)", strings.ToUpper(TOOLS[m.Tool]))
+		titleMsg += `
+LMB/RMB: draw/erase  V: select  TAB: keyboard
+SPACE: presets  B: library  R: rule  CTRL-Z/Y: undo
+O/S: load/save  F/G: skip/run to  T: chart  U: census
+HJKL/MMB: pan  [/]: zoom  M: minimap  Z: age colors
+BACKSPACE: reset  </>: rewind  ENTER: draw life!`
 	case PresetChoosing:
-		titleMsg = fmt.Sprintf("MAP EDITOR\n%s", m.PresetList.View())
+		titleMsg = fmt.Sprintf("MAP EDITOR  Random fill: %s\n%s", m.Soup, m.PresetList.View())
+	case RuleChoosing:
+		titleMsg = fmt.Sprintf("RULES\n%s", m.RuleList.View())
+	case Browsing:
+		titleMsg = m.LibraryList.View()
+	case FileLoading:
+		placement := "centered"
+		if m.AtCursor {
+			placement = "at last click"
+		}
+		titleMsg = fmt.Sprintf(`LOAD PATTERN
+%s
+
+Placement: %s (TAB to toggle)
+ENTER: load
+ESC: cancel`, m.Input.View(), placement)
+	case Paused:
+		titleMsg = `PAUSED
+N: next generation  U: census  Z: age colors
+P/ENTER: resume
+G: run until generation
+SPACE: back to the editor
+BACKSPACE: reset`
+	case Seeking:
+		titleMsg = fmt.Sprintf(`RUN UNTIL GENERATION
+%s
+
+Runs at the current FPS and pauses there
+ENTER: run
+ESC: cancel`, m.Input.View())
+	case Skipping:
+		titleMsg = fmt.Sprintf(`SKIP AHEAD
+%s
+
+Number of generations to step without drawing them
+ENTER: skip
+ESC: cancel`, m.Input.View())
+	case FileSaving:
+		titleMsg = fmt.Sprintf(`SAVE PATTERN
+%s
+
+.cells saves Plaintext, .csv the chart's stats, anything else RLE
+ENTER: save
+ESC: cancel`, m.Input.View())
 	}
+	footer := "Press Esc/Ctrl+C to quit"
+	if m.Status != "" {
+		footer = ansi.Truncate(m.Status, m.Width, "…")
+	}
+	if m.busy {
+		done := m.skip.done.Load()
+		footer = fmt.Sprintf("Skipping ahead %d generations... %d%%  Ctrl+C to quit", m.skip.generations, 100*done/int64(m.skip.generations))
+	} else {
+		m.screen = m.drawScreen()
+	}
+	ruleMsg, st := m.screen.ruleMsg, m.screen.stats
+	z := ZOOMS[m.Zoom]
+	var cycleMsg string
+	if m.cycle != nil {
+		cycleMsg = fmt.Sprintf("  %s", m.cycle)
+	}
+	fpsMsg := fmt.Sprintf("Gen: %d  Pop: %d%s  FPS: %d  -/+  Zoom: %dx%d  View: %d,%d (C: center)", st.Generation, st.Population, cycleMsg, m.FPS, z.Cols, z.Rows, m.viewLeft, m.viewTop)
+	fpsMsg = ansi.Truncate(fpsMsg, m.Width, "…")
 
 	return fmt.Sprintf(
 		`%s
 %s
 %s
 %s
+%s
 %s`,
 		colors[1].Width(m.Width).AlignHorizontal(0.5).Render(titleMsg),
 		colors[2].Width(m.Width).Render(strings.Repeat("=", m.Width)),
-		colors[2].Width(m.Width).AlignHorizontal(0.5).Render(fmt.Sprintf("FPS: %d  ←-/+→", m.FPS)),
-		colors[2].Width(m.Width).AlignHorizontal(0.5).Render("Press Esc/Ctrl+C to quit"),
-		canvas.View(),
+		colors[2].Width(m.Width).AlignHorizontal(0.5).Render(fpsMsg),
+		colors[2].Width(m.Width).AlignHorizontal(0.5).Render(ruleMsg),
+		colors[2].Width(m.Width).AlignHorizontal(0.5).Render(footer),
+		m.screen.panels,
 	)
 }
 
+// screen is the part of the screen drawn from the engine
+type screen struct {
+	ruleMsg string
+	stats   life.Stats
+	panels  string
+}
+
+// drawScreen draws the part of the screen that reads the engine
+func (m *Model) drawScreen() screen {
+	rule := m.GameEngine.Rule()
+	ruleMsg := fmt.Sprintf("Rule: %s", rule)
+	if name := rule.Name(); name != "" {
+		ruleMsg = fmt.Sprintf("Rule: %s (%s)", rule, name)
+	}
+	ruleMsg += fmt.Sprintf("  Topology: %s", m.GameEngine.Topology())
+	if height, width, ok := m.gridSize(); ok {
+		ruleMsg += fmt.Sprintf(" %dx%d", width, height)
+	}
+	if m.filled != nil {
+		ruleMsg += fmt.Sprintf("  Seed: %d", m.filled.Seed)
+	}
+	return screen{
+		ruleMsg: ansi.Truncate(ruleMsg, m.Width, "…"),
+		stats:   m.GameEngine.Stats(),
+		panels:  m.renderPanels(),
+	}
+}
+
 func InitModel(gameEngine *CGL, height int, width int) *Model {
 	m := &Model{
 		GameEngine: gameEngine,
 		GameState:  Mapping,
-		PresetList: list.New([]list.Item{
+		PresetList: newChoiceList([]list.Item{
 			item(RAND),
 			item(EDGES),
 			item(PILLARS),
@@ -349,20 +1040,34 @@ func InitModel(gameEngine *CGL, height int, width int) *Model {
 			item(THREADS),
 			item(CHECKERS),
 			item(DIAMONDS),
-		},
-			itemDelegate{},
-			width, 5,
-		),
-		FPS:       10,
-		EditState: Observing,
-		Height:    height,
-		Width:     width,
+		}, width),
+		FPS:         10,
+		ShowMinimap: true,
+		Palette:     PALETTES[0].Palette,
+		Soup:        life.Soup{Density: life.DEFAULT_DENSITY},
+		EditState:   Observing,
+		Height:      height,
+		Width:       width,
+		Detector:    life.NewDetector(),
+	}
+	rules := make([]list.Item, len(life.RULES))
+	for i, nr := range life.RULES {
+		rules[i] = item(fmt.Sprintf("%s (%s)", nr.Name, nr.Rule))
 	}
-	m.PresetList.SetShowHelp(false)
-	m.PresetList.SetShowTitle(false)
-	m.PresetList.SetShowStatusBar(false)
-	m.PresetList.SetFilteringEnabled(false)
-	m.PresetList.DisableQuitKeybindings()
-	m.PresetList.Styles.PaginationStyle = paginationStyle
+	m.RuleList = newChoiceList(rules, width)
+	m.LibraryList = newLibraryList(width)
+	m.Input = textinput.New()
+	m.Input.Prompt = "> "
 	return m
 }
+
+func newChoiceList(items []list.Item, width int) list.Model {
+	l := list.New(items, itemDelegate{}, width, 5)
+	l.SetShowHelp(false)
+	l.SetShowTitle(false)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	l.Styles.PaginationStyle = paginationStyle
+	return l
+}
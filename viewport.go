@@ -0,0 +1,328 @@
+package main
+
+import (
+	"image"
+
+	ncanvas "github.com/NimbleMarkets/ntcharts/canvas"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Cybergenik/cgl/life"
+)
+
+// Zoom levels, board cells covered by one terminal cell
+var ZOOMS = []struct {
+	Cols, Rows int
+}{
+	{1, 2}, // half blocks
+	{2, 4}, // braille
+	{4, 8}, // density shading from here on
+	{8, 16},
+	{16, 32},
+}
+
+var (
+	// Braille dot bits by row and column within the 4x2 cell
+	brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+	shades      = []rune{'░', '▒', '▓', '█'}
+)
+
+// viewport shifts a universe so that its origin is the top left corner of
+// the screen
+type viewport struct {
+	life.Universe
+	top, left int
+}
+
+func (v viewport) Cell(row, col int) bool {
+	return v.Universe.Cell(row+v.top, col+v.left)
+}
+
+func (v viewport) SetCell(row, col int, alive bool) {
+	v.Universe.SetCell(row+v.top, col+v.left, alive)
+}
+
+func (v viewport) PlacePattern(p *life.Pattern, row, col int) {
+	v.Universe.PlacePattern(p, row+v.top, col+v.left)
+}
+
+// view returns the engine as seen from the screen, all editing goes
+// through it
+func (m *Model) view() viewport {
+	return viewport{m.edits(), m.viewTop, m.viewLeft}
+}
+
+// viewSize returns how many board rows and columns are on screen
+func (m *Model) viewSize() (rows, cols int) {
+	z := ZOOMS[m.Zoom]
+	return m.mapHeight() * z.Rows, m.Width * z.Cols
+}
+
+// gridSize returns the size of a finite map, ok is false on an infinite plane
+func (m *Model) gridSize() (height, width int, ok bool) {
+	g, ok := m.GameEngine.Universe.(life.Finite)
+	if !ok {
+		return 0, 0, false
+	}
+	height, width = g.Size()
+	return height, width, true
+}
+
+// viewRect returns the board cells on screen
+func (m *Model) viewRect() image.Rectangle {
+	rows, cols := m.viewSize()
+	return image.Rect(m.viewLeft, m.viewTop, m.viewLeft+cols, m.viewTop+rows)
+}
+
+// clampView keeps the screen on a finite map
+func (m *Model) clampView() {
+	height, width, ok := m.gridSize()
+	if !ok {
+		return
+	}
+	rows, cols := m.viewSize()
+	m.viewTop = max(min(m.viewTop, height-rows), 0)
+	m.viewLeft = max(min(m.viewLeft, width-cols), 0)
+}
+
+// pan scrolls the screen by a number of terminal rows and columns
+func (m *Model) pan(dy, dx int) {
+	z := ZOOMS[m.Zoom]
+	m.viewTop += dy * z.Rows
+	m.viewLeft += dx * z.Cols
+	m.clampView()
+}
+
+// setZoom switches zoom level keeping the middle of the screen in place
+func (m *Model) setZoom(zoom int) {
+	zoom = max(min(zoom, len(ZOOMS)-1), 0)
+	rows, cols := m.viewSize()
+	midRow, midCol := m.viewTop+rows/2, m.viewLeft+cols/2
+	m.Zoom = zoom
+	rows, cols = m.viewSize()
+	m.viewTop, m.viewLeft = midRow-rows/2, midCol-cols/2
+	m.clampView()
+}
+
+// centerView scrolls the screen so the live cells are in the middle of it
+func (m *Model) centerView() {
+	st := m.GameEngine.Stats()
+	rows, cols := m.viewSize()
+	m.viewTop = st.Y + st.Height/2 - rows/2
+	m.viewLeft = st.X + st.Width/2 - cols/2
+	m.clampView()
+}
+
+// renderMap draws the part of the map on screen at the current zoom level
+func (m *Model) renderMap() string {
+	canvas := ncanvas.New(m.Width, m.mapHeight())
+	canvas.Fill(ncanvas.NewCell(' '))
+	z := ZOOMS[m.Zoom]
+	rows, cols := m.viewSize()
+	var cells [][]bool
+	// Generations rules are drawn in a color per state
+	var states [][]uint8
+	var occupied [][]bool
+	var palette []lipgloss.TerminalColor
+	if rule := m.GameEngine.Rule(); rule.States > 2 {
+		states = m.GameEngine.RegionStates(m.viewTop, m.viewLeft, rows, cols)
+		cells, occupied = make([][]bool, rows), make([][]bool, rows)
+		for i, row := range states {
+			cells[i], occupied[i] = make([]bool, cols), make([]bool, cols)
+			for j, s := range row {
+				cells[i][j], occupied[i][j] = s == 1, s != 0
+			}
+		}
+		palette = decayPalette(rule.States)
+	} else {
+		cells = m.GameEngine.Region(m.viewTop, m.viewLeft, rows, cols)
+	}
+	// Otherwise they can be colored by age instead
+	if m.AgeColors && states == nil {
+		palette = m.Palette.colors(lipgloss.ColorProfile())
+		states = m.ages.classes(cells, m.viewRect(), len(palette)-STABLE-1)
+		occupied = make([][]bool, rows)
+		for i, row := range states {
+			occupied[i] = make([]bool, cols)
+			for j, s := range row {
+				occupied[i][j] = s != 0
+			}
+		}
+	}
+	pending, alive := m.pendingCells(rows, cols)
+	// The map as it would be with the pending change made
+	var after [][]bool
+	if pending != nil {
+		after = make([][]bool, rows)
+		for i := range after {
+			after[i] = make([]bool, cols)
+			for j, p := range pending[i] {
+				after[i][j] = cells[i][j]
+				if p {
+					after[i][j] = alive
+				}
+			}
+		}
+	}
+	var selection image.Rectangle
+	if m.GameState == Mapping {
+		selection = m.Selection
+	}
+	for y := 0; y < m.mapHeight(); y++ {
+		for x := 0; x < m.Width; x++ {
+			style := colors[0]
+			live := glyph(cells, y*z.Rows, x*z.Cols, z.Rows, z.Cols)
+			r := live
+			if states != nil {
+				r, style = decayGlyph(states, occupied, y*z.Rows, x*z.Cols, z.Rows, z.Cols, palette)
+			}
+			if after != nil {
+				// Blocks the pending change would change show it instead
+				if a := glyph(after, y*z.Rows, x*z.Cols, z.Rows, z.Cols); a != live && alive {
+					r, style = a, ghost
+				} else if a != live {
+					r, style = glyph(pending, y*z.Rows, x*z.Cols, z.Rows, z.Cols), erasing
+				}
+			}
+			block := image.Rect(m.viewLeft+x*z.Cols, m.viewTop+y*z.Rows, m.viewLeft+(x+1)*z.Cols, m.viewTop+(y+1)*z.Rows)
+			if block.Overlaps(selection) {
+				style = style.Background(selected)
+			}
+			if m.KeyCursor && m.GameState == Mapping && m.cursor.In(block) {
+				r, style = m.cursorStyle(cells, y, x, r, style)
+			} else if r == ' ' && !block.Overlaps(selection) {
+				continue
+			}
+			canvas.SetRuneWithStyle(image.Point{x, y}, r, style)
+		}
+	}
+	m.drawLabels(&canvas)
+	if m.ShowMinimap {
+		m.drawMinimap(&canvas)
+	}
+	return canvas.View()
+}
+
+// glyph returns the character for the rows x cols block of cells at top, left
+func glyph(cells [][]bool, top, left, rows, cols int) rune {
+	switch {
+	case rows == 2 && cols == 1:
+		upper, lower := cells[top][left], cells[top+1][left]
+		switch {
+		case upper && lower:
+			return '█'
+		case upper:
+			return '▀'
+		case lower:
+			return '▄'
+		}
+		return ' '
+	case rows == 4 && cols == 2:
+		var dots rune
+		for i := range 4 {
+			for j := range 2 {
+				if cells[top+i][left+j] {
+					dots |= brailleDots[i][j]
+				}
+			}
+		}
+		if dots == 0 {
+			return ' '
+		}
+		return 0x2800 + dots
+	}
+	alive := 0
+	for i := top; i < top+rows; i++ {
+		for j := left; j < left+cols; j++ {
+			if cells[i][j] {
+				alive++
+			}
+		}
+	}
+	if alive == 0 {
+		return ' '
+	}
+	// Any life at all shows up, full blocks need more than 3/4 alive
+	return shades[min((alive*len(shades)-1)/(rows*cols), len(shades)-1)]
+}
+
+// drawMinimap overlays the bottom right corner with the whole map (or, on an
+// infinite plane, the live cells and the screen) and where the screen is on it
+func (m *Model) drawMinimap(canvas *ncanvas.Model) {
+	mw, mh := min(m.Width/4, 32), min(m.mapHeight()/3, 10)
+	if mw < 4 || mh < 2 {
+		return
+	}
+	rows, cols := m.viewSize()
+	if height, width, ok := m.gridSize(); ok && height <= rows && width <= cols {
+		// The whole map is on screen already
+		return
+	}
+	st := m.GameEngine.Stats()
+	top, left, bottom, right := m.viewTop, m.viewLeft, m.viewTop+rows, m.viewLeft+cols
+	if height, width, ok := m.gridSize(); ok {
+		top, left, bottom, right = 0, 0, height, width
+	} else if st.Population > 0 {
+		top, left = min(top, st.Y), min(left, st.X)
+		bottom, right = max(bottom, st.Y+st.Height), max(right, st.X+st.Width)
+	}
+	// Board cells per minimap character
+	sy := max((bottom-top+mh-1)/mh, 1)
+	sx := max((right-left+mw-1)/mw, 1)
+	overlaps := func(aTop, aLeft, aBottom, aRight, bTop, bLeft, bBottom, bRight int) bool {
+		return aTop < bBottom && bTop < aBottom && aLeft < bRight && bLeft < aRight
+	}
+	ox, oy := m.Width-mw-2, m.mapHeight()-mh-2
+	for y := -1; y <= mh; y++ {
+		for x := -1; x <= mw; x++ {
+			p := image.Point{ox + 1 + x, oy + 1 + y}
+			var r rune
+			switch {
+			case y == -1 && x == -1:
+				r = '┌'
+			case y == -1 && x == mw:
+				r = '┐'
+			case y == mh && x == -1:
+				r = '└'
+			case y == mh && x == mw:
+				r = '┘'
+			case y == -1 || y == mh:
+				r = '─'
+			case x == -1 || x == mw:
+				r = '│'
+			}
+			if r != 0 {
+				canvas.SetRuneWithStyle(p, r, colors[2])
+				continue
+			}
+			cTop, cLeft := top+y*sy, left+x*sx
+			cBottom, cRight := cTop+sy, cLeft+sx
+			switch {
+			case overlaps(cTop, cLeft, cBottom, cRight, m.viewTop, m.viewLeft, m.viewTop+rows, m.viewLeft+cols):
+				canvas.SetRuneWithStyle(p, '▒', colors[1])
+			case st.Population > 0 && overlaps(cTop, cLeft, cBottom, cRight, st.Y, st.X, st.Y+st.Height, st.X+st.Width):
+				canvas.SetRuneWithStyle(p, '░', colors[0])
+			default:
+				canvas.SetRuneWithStyle(p, '·', colors[2])
+			}
+		}
+	}
+}
+
+// panKey scrolls an eighth of the screen in the direction of a vim key
+func (m *Model) panKey(key string) {
+	// The choice lists use the same keys
+	if m.GameState != Mapping && m.GameState != Playing && m.GameState != Paused {
+		return
+	}
+	dy, dx := max(m.mapHeight()/8, 1), max(m.Width/8, 1)
+	switch key {
+	case "h":
+		m.pan(0, -dx)
+	case "j":
+		m.pan(dy, 0)
+	case "k":
+		m.pan(-dy, 0)
+	case "l":
+		m.pan(0, dx)
+	}
+}